@@ -0,0 +1,44 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var composeCmd = &cobra.Command{
+	Use:   "compose DIR",
+	Short: "assemble the pipeline split in DIR into resources.yml, resource_types.yml, groups.yml and jobs/*.yml",
+	Args:  cobra.ExactArgs(1),
+	RunE:  composeRun,
+}
+
+var (
+	composeDestination string
+)
+
+func composeRun(cmd *cobra.Command, args []string) error {
+	pp, err := ppl.Compose(args[0])
+	if err != nil {
+		return err
+	}
+	if composeDestination != "" {
+		return ioutil.WriteFile(composeDestination, []byte(pp.String()), 0644)
+	}
+	io.WriteString(os.Stdout, pp.String())
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(composeCmd)
+	composeCmd.PersistentFlags().StringVarP(&composeDestination, "destination", "d", "", "destination file to write composed pipeline yaml")
+}
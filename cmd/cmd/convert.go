@@ -6,9 +6,16 @@ Sniperkit-Bot
 package cmd
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
 	"github.com/spf13/cobra"
 
+	"github.com/sniperkit/snk.fork.bulletin/pkg/bulletin_types"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/resource"
 )
 
@@ -18,11 +25,23 @@ var convertCmd = &cobra.Command{
 	RunE:  convertRun,
 }
 
-var target = "."
+var (
+	target          = "."
+	convertRedact   bool
+	convertForce    bool
+	convertVarsFile string
+
+	convertMine           bool
+	convertMinOccurrences int
+	convertOutput         string
+)
 
 const (
 	resourcesDir  = "resources"
 	resourcesFile = "resources.yml"
+
+	varsSkeletonFile = "vars.yml"
+	bulletinFile     = "pipeline.yml"
 )
 
 func convertRun(cmd *cobra.Command, args []string) error {
@@ -30,33 +49,188 @@ func convertRun(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 	p := ioutils.ReadFile(pipeline)
+	var err error
 
 	// update resource types
 	rt := resource.GetResourceTypesFromString(p)
 	savedRT := resource.GetLocalResourceTypes(target)
+	var secrets []resource.Secret
 	for _, r := range rt.ResourceTypes {
+		if convertRedact {
+			var s []resource.Secret
+			r, s, err = r.Redact(rt.ResourceTypes)
+			if err != nil {
+				return err
+			}
+			secrets = append(secrets, s...)
+		}
 		savedRT.Add(r)
 	}
-	err := resource.SaveResourceTypesLocally(target, resource.ResourceTypes{savedRT.Get()})
-	if err != nil {
-		log.Warn("failed to save resource types locally")
-	}
 
 	// update resources
 	rs := resource.GetResourcesFromString(p)
 	savedRs := resource.GetLocalResources(target)
 	for _, r := range rs.Resources {
+		if convertRedact {
+			var s []resource.Secret
+			r, s, err = r.Redact(savedRT.Get())
+			if err != nil {
+				return err
+			}
+			secrets = append(secrets, s...)
+		}
 		savedRs.Add(r)
 	}
+
+	// refuse to persist credentials left in plain text, including the ones
+	// stored by previous runs
+	var plaintext []resource.Secret
+	for _, r := range savedRT.Get() {
+		_, s, err := r.Redact(savedRT.Get())
+		if err != nil {
+			return err
+		}
+		plaintext = append(plaintext, s...)
+	}
+	for _, r := range savedRs.Get() {
+		_, s, err := r.Redact(savedRT.Get())
+		if err != nil {
+			return err
+		}
+		plaintext = append(plaintext, s...)
+	}
+	err = refusePlainText(plaintext)
+	if err != nil {
+		return err
+	}
+
+	err = resource.SaveResourceTypesLocally(target, resource.ResourceTypes{savedRT.Get()})
+	if err != nil {
+		log.Warn("failed to save resource types locally")
+	}
 	err = resource.SaveResourcesLocally(target, resource.Resources{savedRs.Get()})
 	if err != nil {
 		log.Warn("failed to save resources locally")
 	}
 
+	err = saveRedacted(secrets)
+	if err != nil {
+		return err
+	}
+
+	if convertMine {
+		return mineJobs(p)
+	}
 	return nil
 }
 
+// saveRedacted adds the variables replacing secrets to the vars file
+// skeleton, and reports the secrets replaced.
+func saveRedacted(secrets []resource.Secret) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	varsFile := convertVarsFile
+	if varsFile == "" {
+		varsFile = filepath.Join(target, varsSkeletonFile)
+	}
+	err := resource.SaveVarsSkeleton(varsFile, secrets)
+	if err != nil {
+		return err
+	}
+	for _, s := range secrets {
+		fmt.Fprintf(os.Stderr, "%s replaced with ((%s))\n", s.Path, s.Var)
+	}
+	return nil
+}
+
+// refusePlainText fails when credentials are left in plain text, unless
+// --force is set.
+func refusePlainText(plaintext []resource.Secret) error {
+	if len(plaintext) == 0 || convertForce {
+		return nil
+	}
+	for _, s := range plaintext {
+		log.Warn(fmt.Sprintf("plain text credential: %s", s.Path))
+	}
+	return fmt.Errorf("refusing to persist %d plain text credential(s), use --redact or --force", len(plaintext))
+}
+
+// mineJobs saves the steps and decorators mined from the jobs of pipeline p
+// along with the local ones, and writes p as a bulletin pipeline referencing
+// them.
+func mineJobs(p string) error {
+	pp, err := ppl.ParsePipeline(p)
+	if err != nil {
+		return err
+	}
+	savedSteps, err := bulletin_types.LoadLocalSteps(target)
+	if err != nil {
+		return err
+	}
+	savedDecs, err := bulletin_types.LoadLocalDecorators(target)
+	if err != nil {
+		return err
+	}
+	mined, err := bulletin_types.Mine(pp.Jobs, bulletin_types.MineOptions{
+		MinOccurrences: convertMinOccurrences,
+		Steps:          savedSteps,
+		Decorators:     savedDecs,
+	})
+	if err != nil {
+		return err
+	}
+	// mined templates and the params lifted out of them hold the values of
+	// the steps, credentials included
+	redacted, secrets, err := mined.Redact()
+	if err != nil {
+		return err
+	}
+	if convertRedact {
+		mined = redacted
+		err = saveRedacted(secrets)
+	} else {
+		err = refusePlainText(secrets)
+	}
+	if err != nil {
+		return err
+	}
+
+	savedSteps.Steps = append(savedSteps.Steps, mined.Steps.Steps...)
+	err = bulletin_types.SaveStepsLocally(target, savedSteps)
+	if err != nil {
+		return err
+	}
+	savedDecs.Decorators = append(savedDecs.Decorators, mined.Decorators.Decorators...)
+	err = bulletin_types.SaveDecoratorsLocally(target, savedDecs)
+	if err != nil {
+		return err
+	}
+
+	content := mined.Jobs.String()
+	if len(pp.Groups.Groups) != 0 {
+		content = pp.Groups.String() + content
+	}
+	out := convertOutput
+	if out == "" {
+		out = filepath.Join(target, bulletinFile)
+	}
+	err = ioutils.EnsureDir(filepath.Dir(out))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "mined %d step(s) and %d decorator(s), bulletin pipeline written to %s\n",
+		len(mined.Steps.Steps), len(mined.Decorators.Decorators), out)
+	return ioutil.WriteFile(out, []byte(content), 0644)
+}
+
 func init() {
 	rootCmd.AddCommand(convertCmd)
 	convertCmd.PersistentFlags().StringVarP(&target, "target", "t", "", "a folder to persist pipeline components definitions")
+	convertCmd.PersistentFlags().BoolVar(&convertRedact, "redact", true, "replace credentials found in plain text with ((var)) references")
+	convertCmd.PersistentFlags().BoolVar(&convertForce, "force", false, "persist credentials left in plain text")
+	convertCmd.PersistentFlags().StringVar(&convertVarsFile, "vars-file", "", "vars file skeleton to add redacted credentials to, defaults to vars.yml in target folder")
+	convertCmd.PersistentFlags().BoolVar(&convertMine, "mine", false, "extract steps and decorators from the jobs, and rewrite the pipeline as a bulletin pipeline referencing them")
+	convertCmd.PersistentFlags().IntVar(&convertMinOccurrences, "min-occurrences", bulletin_types.DefaultMinOccurrences, "number of times a step or a hook must be found to become a shared template")
+	convertCmd.PersistentFlags().StringVarP(&convertOutput, "output", "o", "", "file to write the bulletin pipeline to, defaults to pipeline.yml in target folder")
 }
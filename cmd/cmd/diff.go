@@ -0,0 +1,60 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/diff"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD NEW",
+	Short: "report added, removed and changed components between two pipelines",
+	Args:  cobra.ExactArgs(2),
+	RunE:  diffRun,
+}
+
+var (
+	diffFormat   string
+	diffExitCode bool
+)
+
+func diffRun(cmd *cobra.Command, args []string) error {
+	a, err := ppl.LoadPipeline(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := ppl.LoadPipeline(args[1])
+	if err != nil {
+		return err
+	}
+	d, err := diff.Pipelines(a, b)
+	if err != nil {
+		return err
+	}
+	out, err := d.Render(diffFormat)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+	if diffFormat == diff.JSONFormat {
+		fmt.Println()
+	}
+	if diffExitCode && !d.Empty() {
+		return fmt.Errorf("found %d difference(s)", len(d.Changes))
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.PersistentFlags().StringVarP(&diffFormat, "format", "f", diff.TextFormat, "output format: text or json")
+	diffCmd.PersistentFlags().BoolVar(&diffExitCode, "exit-code", false, "fail when the pipelines differ")
+}
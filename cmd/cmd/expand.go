@@ -11,16 +11,30 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/sniperkit/snk.fork.bulletin/pkg/bulletin_types"
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/group"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/resource"
 )
 
 var expandCmd = &cobra.Command{
 	Use:   "expand",
 	Short: "expand bulletin pipeline yaml file to concourse configuration yaml file",
-	RunE:  expandRun,
+	Long: `Expand the jobs of a bulletin pipeline and emit a complete concourse
+pipeline: the resources its steps get or put and the custom resource types
+they use are taken from the resources and resource types of the target, along
+with the groups of the bulletin pipeline and of its matrices. Resources and
+resource types defined in the bulletin pipeline itself take precedence.
+Expanding fails on names found nowhere.`,
+	RunE: expandRun,
 }
 
-var expandTarget = "."
+var (
+	expandTarget     = "."
+	expandInParallel bool
+)
 
 func expandRun(cmd *cobra.Command, args []string) error {
 	datas := ioutils.ReadFileDefaultStdin(pipeline)
@@ -29,24 +43,75 @@ func expandRun(cmd *cobra.Command, args []string) error {
 	deps := bulletin_types.GetDepsFromString(datas)
 	savedDecs := bulletin_types.GetLocalDecorators(expandTarget)
 	savedSteps := bulletin_types.GetLocalSteps(expandTarget)
+	savedJobs := bulletin_types.GetLocalJobTemplates(expandTarget)
+	err := jobs.Instantiate(savedJobs)
+	if err != nil {
+		return err
+	}
 
 	globalDecs := bulletin_types.GetStepDecoratorDefsFromString(datas)
 	for _, gdec := range globalDecs.Decorators {
 		for _, jt := range gdec.Decorate {
-			job, task := gdec.GetJobTask(jt)
-			jobs.AddDecorator(job, task, gdec.TemplateRef)
+			sel, err := bulletin_types.ParseSelector(jt)
+			if err != nil {
+				return berror.WithPath(err, fmt.Sprintf("decorators[%s].decorate", gdec.Name))
+			}
+			jobs.AddSelectorDecorator(sel, gdec.TemplateRef)
 		}
 	}
 
 	cjobs := jobs.Convert(savedDecs, savedSteps)
-	for _, d := range deps.Deps {
-		d.AddResource(cjobs)
+	parallel := job.AggregateStepType
+	if expandInParallel {
+		parallel = job.InParallelStepType
+	}
+	err = deps.AddResourcesAs(cjobs, parallel)
+	if err != nil {
+		return err
+	}
+	groups, err := group.ParseGroups(datas)
+	if err != nil {
+		return err
+	}
+	resources, err := resource.ParseResources(datas)
+	if err != nil {
+		return err
+	}
+	resourceTypes, err := resource.ParseResourceTypes(datas)
+	if err != nil {
+		return err
+	}
+	p := ppl.Pipeline{
+		Resources:     resources,
+		ResourceTypes: resourceTypes,
+		// groups of the jobs generated by matrices
+		Groups: groups.UpdateWith(jobs.Groups()),
+		Jobs:   cjobs,
+	}
+	savedResources, err := resource.LoadLocalResources(expandTarget)
+	if err != nil {
+		return err
+	}
+	savedResourceTypes, err := resource.LoadLocalResourceTypes(expandTarget)
+	if err != nil {
+		return err
+	}
+	err = p.Resolve(savedResources.Get(), savedResourceTypes.Get())
+	if err != nil {
+		return err
+	}
+	content := p.String()
+	out, err := interpolate(content)
+	if err != nil {
+		return err
 	}
-	fmt.Printf("%s\n", cjobs.String())
+	fmt.Printf("%s\n", out)
 	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(expandCmd)
+	addVarsFlags(expandCmd)
 	expandCmd.PersistentFlags().StringVarP(&expandTarget, "target", "t", "", "a folder to persist pipeline components definitions")
+	expandCmd.PersistentFlags().BoolVar(&expandInParallel, "in-parallel", false, "emit in_parallel steps instead of deprecated aggregate steps")
 }
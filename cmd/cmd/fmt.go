@@ -0,0 +1,93 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [FILE...]",
+	Short: "canonicalize pipeline files: stable section and key order, resources and resource types sorted by name",
+	Long: `canonicalize pipeline files: stable section and key order, resources and
+resource types sorted by name, comments kept. Files are read from the
+arguments, or from --pipeline or stdin when none is given, and printed unless
+--write or --check is set.`,
+	RunE: fmtRun,
+}
+
+var (
+	fmtCheck    bool
+	fmtWrite    bool
+	fmtSortJobs bool
+)
+
+func fmtRun(cmd *cobra.Command, args []string) error {
+	opts := ppl.FormatOptions{SortJobs: fmtSortJobs}
+	if len(args) == 0 {
+		datas := ioutils.ReadFileDefaultStdin(pipeline)
+		out, err := ppl.Format(datas, opts)
+		if err != nil {
+			return err
+		}
+		if fmtCheck {
+			if out != datas {
+				return fmt.Errorf("pipeline is not formatted")
+			}
+			return nil
+		}
+		if fmtWrite && pipeline != "" {
+			return ioutil.WriteFile(pipeline, []byte(out), 0644)
+		}
+		fmt.Print(out)
+		return nil
+	}
+
+	unformatted := 0
+	for _, f := range args {
+		datas, err := ioutils.LoadFile(f)
+		if err != nil {
+			return err
+		}
+		out, err := ppl.Format(datas, opts)
+		if err != nil {
+			return fmt.Errorf("%s: %v", f, err)
+		}
+		switch {
+		case fmtCheck:
+			if out != datas {
+				fmt.Printf("%s\n", f)
+				unformatted++
+			}
+		case fmtWrite:
+			if out != datas {
+				err = ioutil.WriteFile(f, []byte(out), 0644)
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			fmt.Print(out)
+		}
+	}
+	if unformatted != 0 {
+		return fmt.Errorf("%d file(s) are not formatted", unformatted)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+	fmtCmd.Flags().BoolVarP(&fmtCheck, "check", "c", false, "only report the files that are not formatted, failing when there are some")
+	fmtCmd.Flags().BoolVarP(&fmtWrite, "write", "w", false, "rewrite the files in place")
+	fmtCmd.Flags().BoolVarP(&fmtSortJobs, "sort-jobs", "", false, "order jobs after the jobs their passed constraints name")
+}
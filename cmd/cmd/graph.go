@@ -0,0 +1,51 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/graph"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "render job/resource dependencies of provided pipeline as dot, mermaid or json",
+	RunE:  graphRun,
+}
+
+var (
+	graphFormat   string
+	graphJobsOnly bool
+)
+
+func graphRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	pp := ppl.GetPipelineFromString(datas)
+	g, err := graph.NewGraph(pp)
+	if err != nil {
+		return err
+	}
+	if graphJobsOnly {
+		g = g.JobsOnly()
+	}
+	out, err := g.Render(graphFormat)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", out)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.PersistentFlags().StringVarP(&graphFormat, "format", "f", graph.DOTFormat, "output format: dot, mermaid or json")
+	graphCmd.PersistentFlags().BoolVarP(&graphJobsOnly, "jobs-only", "j", false, "only render jobs and the passed constraints between them")
+}
@@ -10,8 +10,9 @@ import (
 )
 
 var groupCmd = &cobra.Command{
-	Use:   "group",
-	Short: "managing all the groups defined in pipeline.yml",
+	Use:     "group",
+	Aliases: []string{"groups"},
+	Short:   "managing all the groups defined in pipeline.yml",
 }
 
 func init() {
@@ -0,0 +1,78 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/graph"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var groupGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "generate groups from the jobs of provided pipeline and the passed constraints between them",
+	RunE:  groupGenerateRun,
+}
+
+var (
+	groupStrategy  string
+	groupSeparator string
+	groupPatterns  []string
+	groupDefault   string
+	groupAll       bool
+	groupMerge     bool
+)
+
+func groupGenerateRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	pp, err := ppl.ParsePipeline(datas)
+	if err != nil {
+		return err
+	}
+	g, err := graph.NewGraph(pp)
+	if err != nil {
+		return err
+	}
+	groups, err := g.Groups(graph.GroupOptions{
+		Strategy:  groupStrategy,
+		Separator: groupSeparator,
+		Patterns:  groupPatterns,
+		Default:   groupDefault,
+		All:       groupAll,
+	})
+	if err != nil {
+		return err
+	}
+	if groupMerge {
+		// hand-written groups come first and lose the jobs and resources
+		// that are gone
+		var jobs, resources []string
+		for _, j := range pp.Jobs.Jobs {
+			jobs = append(jobs, j.Name)
+		}
+		for _, r := range pp.Resources.Resources {
+			resources = append(resources, r.Name)
+		}
+		groups = pp.Groups.UpdateWith(groups)
+		groups = groups.Prune(jobs, resources)
+	}
+	fmt.Printf("%s", groups.String())
+	return nil
+}
+
+func init() {
+	groupCmd.AddCommand(groupGenerateCmd)
+	groupGenerateCmd.PersistentFlags().StringVarP(&groupStrategy, "strategy", "s", graph.ComponentsStrategy, "grouping strategy: components, prefix or regex")
+	groupGenerateCmd.PersistentFlags().StringVar(&groupSeparator, "separator", graph.DefaultGroupSeparator, "separator ending the job name prefix with the prefix strategy")
+	groupGenerateCmd.PersistentFlags().StringArrayVarP(&groupPatterns, "regex", "r", nil, "regex naming the group of the jobs it matches with the regex strategy, after its submatch named group or its first submatch")
+	groupGenerateCmd.PersistentFlags().StringVar(&groupDefault, "default", graph.DefaultGroup, "group of the jobs no regex matches")
+	groupGenerateCmd.PersistentFlags().BoolVar(&groupAll, "all", false, "add a group holding every job")
+	groupGenerateCmd.PersistentFlags().BoolVarP(&groupMerge, "merge", "m", false, "merge generated groups into the groups defined in provided pipeline")
+}
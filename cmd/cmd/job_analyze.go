@@ -0,0 +1,127 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var jobAnalyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "follow the artifacts through the plan of jobs",
+	Long: `follow the artifacts through the plan of jobs: the artifacts available to
+every step, the steps producing and consuming them, and the problems of their
+wiring: inputs no earlier step produces, put params pointing to missing
+artifacts, unknown input or output mappings. Put params are taken as paths
+when the catalog documents them as such, or when they point into an artifact
+produced earlier; other values looking like paths, as well as artifacts
+produced but never used, are reported without failing.`,
+	RunE: jobAnalyzeRun,
+}
+
+var (
+	analyzeTasksDir  string
+	analyzeArtifacts []string
+)
+
+func jobAnalyzeRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	p, err := ppl.ParsePipeline(datas)
+	if err != nil {
+		return err
+	}
+	jobs := p.Jobs
+	paths := p.PathParams()
+	r, err := newTaskConfigResolver(analyzeTasksDir, analyzeArtifacts)
+	if err != nil {
+		return err
+	}
+	if jobName != "" {
+		if _, err := jobs.GetJob(jobName); err != nil {
+			return fmt.Errorf("%s: %v", jobName, err)
+		}
+	}
+
+	problems := 0
+	for _, j := range jobs.Jobs {
+		if jobName != "" && j.Name != jobName {
+			continue
+		}
+		flow, err := j.ArtifactFlow(r, paths)
+		if err != nil {
+			return fmt.Errorf("jobs[%s]: %v", j.Name, err)
+		}
+		printArtifactFlow(j.Name, flow)
+		problems += len(flow.Problems)
+	}
+	if problems != 0 {
+		return fmt.Errorf("found %d artifact problem(s)", problems)
+	}
+	return nil
+}
+
+func printArtifactFlow(name string, flow job.ArtifactFlow) {
+	fmt.Printf("jobs[%s]:\n", name)
+	fmt.Printf("  steps:\n")
+	for _, s := range flow.Steps {
+		available := strings.Join(s.Available, ", ")
+		if available == "" {
+			available = "-"
+		}
+		fmt.Printf("    %-40s %-12s %s\n", s.Step, s.Type, available)
+	}
+	if len(flow.Artifacts) != 0 {
+		fmt.Printf("  artifacts:\n")
+	}
+	for _, a := range flow.Artifacts {
+		var uses []string
+		for _, i := range flow.Inputs {
+			if i.Name == a.Name && i.Producer == a.Step {
+				uses = append(uses, fmt.Sprintf("%s (%s)", i.Step, i.Use))
+			}
+		}
+		used := "unused"
+		if a.Type == job.PutStepType {
+			used = "-"
+		}
+		if len(uses) != 0 {
+			used = "used by " + strings.Join(uses, ", ")
+		}
+		fmt.Printf("    %-20s from %-30s %s\n", a.Name, a.Step, used)
+	}
+	unused := flow.Unused()
+	if len(unused) != 0 {
+		fmt.Printf("  unused:\n")
+	}
+	for _, a := range unused {
+		fmt.Printf("    %s: artifact %q of %s step is never used\n", a.Step, a.Name, a.Type)
+	}
+	if len(flow.Problems) != 0 {
+		fmt.Printf("  problems:\n")
+	}
+	for _, p := range flow.Problems {
+		fmt.Printf("    %v\n", p)
+	}
+	if len(flow.Warnings) != 0 {
+		fmt.Printf("  warnings:\n")
+	}
+	for _, w := range flow.Warnings {
+		fmt.Printf("    %v\n", w)
+	}
+}
+
+func init() {
+	jobCmd.AddCommand(jobAnalyzeCmd)
+	jobAnalyzeCmd.Flags().StringVarP(&analyzeTasksDir, "tasks-dir", "", "", "directory holding the artifacts task files are read from, one folder per artifact")
+	jobAnalyzeCmd.Flags().StringArrayVarP(&analyzeArtifacts, "artifact", "a", nil, "local directory of an artifact task files are read from, as NAME=DIR")
+}
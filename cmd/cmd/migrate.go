@@ -0,0 +1,39 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "rewrite deprecated aggregate steps of provided pipeline as in_parallel steps",
+	RunE:  migrateRun,
+}
+
+func migrateRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	pp, err := ppl.ParsePipeline(datas)
+	if err != nil {
+		return err
+	}
+	err = pp.MigrateAggregates()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", pp.String())
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
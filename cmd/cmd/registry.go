@@ -0,0 +1,40 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	reg "github.com/sniperkit/snk.fork.bulletin/pkg/registry"
+)
+
+var registryCmd = &cobra.Command{
+	Use:   "registry",
+	Short: "managing namespaced, versioned components stored in specified folder",
+}
+
+var (
+	registryTarget string
+	registryKind   string
+)
+
+func checkRegistryKind() error {
+	for _, k := range reg.Kinds {
+		if k == registryKind {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported kind %q, expected one of: %s", registryKind, strings.Join(reg.Kinds, ", "))
+}
+
+func init() {
+	rootCmd.AddCommand(registryCmd)
+	registryCmd.PersistentFlags().StringVarP(&registryTarget, "target", "t", ".", "a folder to persist pipeline components definitions")
+	registryCmd.PersistentFlags().StringVarP(&registryKind, "kind", "k", reg.StepsKind, "kind of components: steps, decorators or jobs")
+}
@@ -0,0 +1,45 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	reg "github.com/sniperkit/snk.fork.bulletin/pkg/registry"
+)
+
+var registryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list components with all their versions",
+	RunE:  registryListRun,
+}
+
+var registryNamespace string
+
+func registryListRun(cmd *cobra.Command, args []string) error {
+	err := checkRegistryKind()
+	if err != nil {
+		return err
+	}
+	refs, err := reg.New(registryTarget).List(registryKind)
+	if err != nil {
+		return err
+	}
+	for _, r := range refs {
+		if registryNamespace != "" && registryNamespace != r.Namespace {
+			continue
+		}
+		fmt.Printf("%s\n", r.String())
+	}
+	return nil
+}
+
+func init() {
+	registryCmd.AddCommand(registryListCmd)
+	registryListCmd.Flags().StringVarP(&registryNamespace, "namespace", "n", "", "only list components of provided namespace")
+}
@@ -0,0 +1,54 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	reg "github.com/sniperkit/snk.fork.bulletin/pkg/registry"
+)
+
+var registryPublishCmd = &cobra.Command{
+	Use:   "publish",
+	Short: "publish a component definition as namespace/name@version",
+	RunE:  registryPublishRun,
+}
+
+var (
+	registryRef   string
+	registryFile  string
+	registryForce bool
+)
+
+func registryPublishRun(cmd *cobra.Command, args []string) error {
+	err := checkRegistryKind()
+	if err != nil {
+		return err
+	}
+	ref, err := reg.ParseRef(registryRef)
+	if err != nil {
+		return err
+	}
+	datas := ioutils.ReadFileDefaultStdin(registryFile)
+	def := make(map[string]interface{})
+	err = yaml.Unmarshal([]byte(datas), &def)
+	if err != nil {
+		return fmt.Errorf("invalid %s definition: %v", registryKind, err)
+	}
+	return reg.New(registryTarget).Publish(registryKind, ref, datas, registryForce)
+}
+
+func init() {
+	registryCmd.AddCommand(registryPublishCmd)
+	registryPublishCmd.Flags().StringVarP(&registryRef, "ref", "r", "", "reference of the component, e.g. team-a/git-repo@1.0.0")
+	registryPublishCmd.Flags().StringVarP(&registryFile, "file", "f", "", "a file holding the component definition, read from stdin by default")
+	registryPublishCmd.Flags().BoolVar(&registryForce, "force", false, "overwrite an already published version")
+	registryPublishCmd.MarkFlagRequired("ref")
+}
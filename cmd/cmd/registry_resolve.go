@@ -0,0 +1,42 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	reg "github.com/sniperkit/snk.fork.bulletin/pkg/registry"
+)
+
+var registryResolveCmd = &cobra.Command{
+	Use:   "resolve REF",
+	Short: "print the version a reference such as team-a/git-repo@1.x resolves to, and its definition",
+	Args:  cobra.ExactArgs(1),
+	RunE:  registryResolveRun,
+}
+
+func registryResolveRun(cmd *cobra.Command, args []string) error {
+	err := checkRegistryKind()
+	if err != nil {
+		return err
+	}
+	ref, err := reg.ParseRef(args[0])
+	if err != nil {
+		return err
+	}
+	res, data, err := reg.New(registryTarget).Load(registryKind, ref)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("# %s\n%s\n", res.String(), data)
+	return nil
+}
+
+func init() {
+	registryCmd.AddCommand(registryResolveCmd)
+}
@@ -7,6 +7,7 @@ package cmd
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/spf13/cobra"
 
@@ -21,51 +22,98 @@ var resourceListCmd = &cobra.Command{
 }
 
 var (
-	resourceName      string
-	resourceType      string
-	listResourceNames bool
-	listResourceTypes bool
+	resourceName        string
+	resourceType        string
+	listResourceNames   bool
+	listResourceTypes   bool
+	listResourceDocs    bool
+	checkResourceSource bool
 )
 
 func resourceListRun(cmd *cobra.Command, args []string) error {
+	if listResourceDocs && pipeline == "" {
+		// without pipeline, every type of the catalog is documented
+		for _, t := range resource.CatalogTypes() {
+			if resourceType == "" || resourceType == t {
+				d, _ := resource.GetTypeDoc(t)
+				fmt.Printf("%s", d.String())
+			}
+		}
+		return nil
+	}
 
 	datas := ioutils.ReadFileDefaultStdin(pipeline)
 	resources := resource.GetResourcesFromString(datas)
-	printTypes := make(map[string]interface{})
-	if listResourceNames {
-		for _, r := range resources.Resources {
+	resourceTypes := resource.GetResourceTypesFromString(datas)
+	declared := resourceTypes.Map()
+	kind := func(t string) string {
+		if _, ok := declared[t]; ok {
+			return "custom"
+		}
+		if resource.IsCoreResourceType(t) {
+			return "core"
+		}
+		return "unknown"
+	}
+
+	var selected []resource.Resource
+	for _, r := range resources.Resources {
+		if (resourceType == "" || resourceType == r.Type) && (resourceName == "" || resourceName == r.Name) {
+			selected = append(selected, r)
+		}
+	}
+
+	// declared types replace the core ones, their source is not documented
+	unknown := 0
+	for _, r := range selected {
+		if _, ok := declared[r.Type]; ok {
+			continue
+		}
+		fields, err := r.UnknownSourceFields()
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			unknown++
+			log.Warn(fmt.Sprintf("resources[%s].source.%s: not a documented field of %s sources", r.Name, f, r.Type))
+		}
+	}
+
+	types := make(map[string]bool)
+	var typeNames []string
+	for _, r := range selected {
+		if !types[r.Type] {
+			types[r.Type] = true
+			typeNames = append(typeNames, r.Type)
+		}
+	}
+	sort.Strings(typeNames)
+
+	switch {
+	case listResourceNames:
+		for _, r := range selected {
 			fmt.Printf("%s\n", r.Name)
 		}
-	} else if listResourceTypes {
-		for _, r := range resources.Resources {
-			printTypes[r.Type] = nil
+	case listResourceTypes:
+		for _, t := range typeNames {
+			fmt.Printf("%s\t%s\n", t, kind(t))
 		}
-	} else {
-		for _, r := range resources.Resources {
-			if resourceType == "" && resourceName == "" {
-				fmt.Printf("%+v\n", r.String())
+	case listResourceDocs:
+		for _, t := range typeNames {
+			d, ok := resource.GetTypeDoc(t)
+			if !ok || kind(t) == "custom" {
+				fmt.Printf("%s (%s): undocumented\n", t, kind(t))
 				continue
 			}
-			if resourceType != "" && resourceType == r.Type {
-				if resourceName != "" && resourceName == r.Name {
-					fmt.Printf("%+v\n", r.String())
-					continue
-				}
-				if resourceName == "" {
-					fmt.Printf("%+v\n", r.String())
-					continue
-				}
-			}
-			if resourceName != "" && resourceName == r.Name {
-				if resourceType == "" {
-					fmt.Printf("%+v\n", r.String())
-					continue
-				}
-			}
+			fmt.Printf("%s", d.String())
+		}
+	default:
+		for _, r := range selected {
+			fmt.Printf("%+v\n", r.String())
 		}
 	}
-	for k, _ := range printTypes {
-		fmt.Printf("%s\n", k)
+	if checkResourceSource && unknown != 0 {
+		return fmt.Errorf("%d undocumented source fields", unknown)
 	}
 	return nil
 }
@@ -76,5 +124,7 @@ func init() {
 	resourceCmd.PersistentFlags().StringVarP(&resourceName, "resource-name", "", "", "list all resources based on provided name")
 	resourceCmd.PersistentFlags().StringVarP(&resourceType, "resource-type", "", "", "list all resources based on provided type")
 	resourceCmd.PersistentFlags().BoolVarP(&listResourceNames, "name", "n", false, "list all resources names")
-	resourceCmd.PersistentFlags().BoolVarP(&listResourceTypes, "type", "t", false, "list all resources types")
+	resourceCmd.PersistentFlags().BoolVarP(&listResourceTypes, "type", "t", false, "list all resources types, along with their kind: core, custom when declared in resource_types, or unknown")
+	resourceListCmd.Flags().BoolVarP(&listResourceDocs, "docs", "", false, "show the documented source, get params and put params fields of the resources types, of every type of the catalog when no pipeline is given")
+	resourceListCmd.Flags().BoolVarP(&checkResourceSource, "check", "", false, "fail when the source of a resource holds fields its type does not document")
 }
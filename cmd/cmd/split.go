@@ -0,0 +1,38 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var splitCmd = &cobra.Command{
+	Use:   "split DIR",
+	Short: "split provided pipeline into resources.yml, resource_types.yml, groups.yml and jobs/*.yml in DIR, the reverse of compose",
+	Args:  cobra.ExactArgs(1),
+	RunE:  splitRun,
+}
+
+var (
+	splitForce bool
+)
+
+func splitRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	pp, err := ppl.ParsePipeline(datas)
+	if err != nil {
+		return err
+	}
+	return pp.Split(args[0], splitForce)
+}
+
+func init() {
+	rootCmd.AddCommand(splitCmd)
+	splitCmd.PersistentFlags().BoolVar(&splitForce, "force", false, "overwrite a pipeline already split in DIR")
+}
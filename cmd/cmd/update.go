@@ -14,6 +14,7 @@ import (
 
 	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
 	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/yamlnode"
 )
 
 var updateCmd = &cobra.Command{
@@ -34,13 +35,27 @@ func updateRun(cmd *cobra.Command, args []string) error {
 		tp := ppl.GetPipelineFromString(ioutils.ReadFile(f))
 		pp.UpdateWith(tp)
 	}
+	// edit the original document rather than printing the model so that
+	// comments, key order and anchors are kept
+	doc, err := yamlnode.ParseDocument(datas)
+	if err != nil {
+		return err
+	}
+	err = doc.Update(pp)
+	if err != nil {
+		return err
+	}
+	out, err := interpolate(doc.String())
+	if err != nil {
+		return err
+	}
 	if destination != "" {
-		err := ioutil.WriteFile(destination, []byte(pp.String()), 0644)
+		err := ioutil.WriteFile(destination, []byte(out), 0644)
 		if err != nil {
 			return err
 		}
 	} else {
-		io.WriteString(os.Stdout, pp.String())
+		io.WriteString(os.Stdout, out)
 	}
 
 	return nil
@@ -48,5 +63,6 @@ func updateRun(cmd *cobra.Command, args []string) error {
 
 func init() {
 	rootCmd.AddCommand(updateCmd)
+	addVarsFlags(updateCmd)
 	updateCmd.PersistentFlags().StringVarP(&destination, "destination", "d", "", "destination file to write updated pipeline yaml")
 }
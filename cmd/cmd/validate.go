@@ -0,0 +1,76 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	ppl "github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "report configuration errors Concourse would reject in provided pipeline",
+	Long: `report configuration errors Concourse would reject in provided pipeline,
+along with the steps consuming artifacts no earlier step produces. Tasks
+defined in a file are checked when --tasks-dir or --artifact locate the file.`,
+	RunE: validateRun,
+}
+
+var (
+	validateTasksDir  string
+	validateArtifacts []string
+)
+
+func validateRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	pp, err := ppl.ParsePipeline(datas)
+	if err != nil {
+		return err
+	}
+	violations := pp.Validate()
+	r, err := newTaskConfigResolver(validateTasksDir, validateArtifacts)
+	if err != nil {
+		return err
+	}
+	violations = append(violations, pp.CheckArtifacts(r)...)
+	for _, v := range violations {
+		fmt.Printf("%s\n", v.String())
+	}
+	if len(violations) != 0 {
+		return fmt.Errorf("found %d problem(s) in pipeline", len(violations))
+	}
+	return nil
+}
+
+// newTaskConfigResolver returns the resolver of the task files found in dir
+// or in the directories of artifacts, given as NAME=DIR. Task files are not
+// resolved when both are empty.
+func newTaskConfigResolver(dir string, artifacts []string) (job.TaskConfigResolver, error) {
+	if dir == "" && len(artifacts) == 0 {
+		return nil, nil
+	}
+	r := &job.DirResolver{Root: dir, Artifacts: make(map[string]string)}
+	for _, a := range artifacts {
+		parts := strings.SplitN(a, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid artifact %q, expected NAME=DIR", a)
+		}
+		r.Artifacts[parts[0]] = parts[1]
+	}
+	return r, nil
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().StringVarP(&validateTasksDir, "tasks-dir", "", "", "directory holding the artifacts task files are read from, one folder per artifact")
+	validateCmd.Flags().StringArrayVarP(&validateArtifacts, "artifact", "a", nil, "local directory of an artifact task files are read from, as NAME=DIR")
+}
@@ -0,0 +1,89 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/vars"
+)
+
+var varsCmd = &cobra.Command{
+	Use:   "vars",
+	Short: "managing ((var)) placeholders of provided pipeline",
+}
+
+var (
+	varsFlags     []string
+	varsFiles     []string
+	varsEnvPrefix string
+	varsStore     string
+)
+
+// addVarsFlags adds the flags defining variable sources to cmd.
+func addVarsFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringArrayVarP(&varsFlags, "vars", "v", nil, "a name=value variable, value being parsed as yaml, can be repeated")
+	cmd.PersistentFlags().StringArrayVarP(&varsFiles, "load-vars-from", "l", nil, "a yaml file defining variables, can be repeated")
+	cmd.PersistentFlags().StringVar(&varsEnvPrefix, "vars-env-prefix", "", "read variables from environment variables with provided prefix")
+	cmd.PersistentFlags().StringVar(&varsStore, "vars-store", "", "a folder holding one yaml file per variable")
+}
+
+// varsSource returns the variable sources set by the flags, command line
+// variables taking precedence over vars files, then environment and store.
+// It returns nil when no source is set.
+func varsSource() (vars.Source, error) {
+	var res vars.MultiSource
+	if len(varsFlags) != 0 {
+		static := vars.StaticVariables{}
+		for _, f := range varsFlags {
+			k, v, err := vars.ParseVarFlag(f)
+			if err != nil {
+				return nil, err
+			}
+			static[k] = v
+		}
+		res = append(res, static)
+	}
+	// later files override earlier ones, like fly does
+	for i := len(varsFiles) - 1; i >= 0; i-- {
+		static, err := vars.LoadVarsFile(varsFiles[i])
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, static)
+	}
+	if varsEnvPrefix != "" {
+		res = append(res, vars.EnvVariables{Prefix: varsEnvPrefix})
+	}
+	if varsStore != "" {
+		res = append(res, vars.FileStore{Dir: varsStore})
+	}
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return res, nil
+}
+
+// interpolate replaces the placeholders of the yaml document data when
+// variable sources are set. Undefined variables are left for the credential
+// manager, like fly does, with a warning.
+func interpolate(data string) (string, error) {
+	src, err := varsSource()
+	if err != nil || src == nil {
+		return data, err
+	}
+	out, err := vars.InterpolateString(data, src)
+	if e, ok := err.(*vars.UndefinedError); ok {
+		log.Warn(e.Error())
+		return out, nil
+	}
+	return out, err
+}
+
+func init() {
+	rootCmd.AddCommand(varsCmd)
+	addVarsFlags(varsCmd)
+}
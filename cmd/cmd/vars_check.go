@@ -0,0 +1,56 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/vars"
+)
+
+var varsCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "report variables of provided pipeline that are undefined, and defined variables that are unused",
+	RunE:  varsCheckRun,
+}
+
+func varsCheckRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	var doc interface{}
+	err := yaml.Unmarshal([]byte(datas), &doc)
+	if err != nil {
+		return err
+	}
+	src, err := varsSource()
+	if err != nil {
+		return err
+	}
+	if src == nil {
+		src = vars.StaticVariables{}
+	}
+	report, err := vars.Check(doc, src)
+	if err != nil {
+		return err
+	}
+	for _, n := range report.Undefined {
+		fmt.Printf("undefined: %s\n", n)
+	}
+	for _, n := range report.Unused {
+		fmt.Printf("unused: %s\n", n)
+	}
+	if len(report.Undefined) != 0 {
+		return fmt.Errorf("found %d undefined var(s)", len(report.Undefined))
+	}
+	return nil
+}
+
+func init() {
+	varsCmd.AddCommand(varsCheckCmd)
+}
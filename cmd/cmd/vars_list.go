@@ -0,0 +1,48 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/vars"
+)
+
+var varsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list variables referenced by provided pipeline",
+	RunE:  varsListRun,
+}
+
+var listVarsRefs bool
+
+func varsListRun(cmd *cobra.Command, args []string) error {
+	datas := ioutils.ReadFileDefaultStdin(pipeline)
+	var doc interface{}
+	err := yaml.Unmarshal([]byte(datas), &doc)
+	if err != nil {
+		return err
+	}
+	if listVarsRefs {
+		for _, r := range vars.Find(doc) {
+			fmt.Printf("%s: %s\n", r.Path, r.Var.String())
+		}
+		return nil
+	}
+	for _, n := range vars.Names(doc) {
+		fmt.Printf("%s\n", n)
+	}
+	return nil
+}
+
+func init() {
+	varsCmd.AddCommand(varsListCmd)
+	varsListCmd.Flags().BoolVarP(&listVarsRefs, "references", "r", false, "list every reference along with its location")
+}
@@ -8,8 +8,8 @@ package bulletin_types
 import (
 	"errors"
 	"fmt"
-	"log"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	template "github.com/maplain/yamltemplate"
@@ -18,12 +18,20 @@ import (
 	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/registry"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/yamlnode"
 )
 
 const (
 	decoratorsDir  = "decorators"
 	decoratorsFile = "decorators.yml"
+
+	// AppendMerge chains the hooks of a decorator after the ones the step
+	// already has, in a do step, and adds its tags to the step ones.
+	AppendMerge = "append"
+	// ReplaceMerge overwrites the hooks and tags the step already has.
+	ReplaceMerge = "replace"
 )
 
 type StepDecoratorDefs struct {
@@ -37,27 +45,44 @@ func (d *StepDecoratorDefs) String() string {
 }
 
 func GetStepDecoratorDefsFromString(data string) StepDecoratorDefs {
-	r := StepDecoratorDefs{}
-	err := yaml.Unmarshal([]byte(data), &r)
+	r, err := ParseStepDecoratorDefs(data)
 	berror.CheckError(err)
 	return r
 }
 
+// ParseStepDecoratorDefs is the error returning counterpart of GetStepDecoratorDefsFromString.
+func ParseStepDecoratorDefs(data string) (StepDecoratorDefs, error) {
+	r := StepDecoratorDefs{}
+	err := yaml.Unmarshal([]byte(data), &r)
+	if err != nil {
+		return r, berror.FromYAML(err)
+	}
+	return r, nil
+}
+
 type StepDecoratorDef struct {
 	template.TemplateRef `yaml:",inline"`
-	Decorate             []string `yaml:"decorate"`
+	// Decorate lists the selectors of the jobs and steps to decorate, see
+	// Selector.
+	Decorate []string `yaml:"decorate"`
 }
 
 func (d *StepDecoratorDef) GetJobTask(s string) (string, string) {
-	res := strings.Split(s, "/")
-	if len(res) == 2 {
-		return res[0], res[1]
+	job, task, err := d.SplitJobTask(s)
+	berror.CheckError(err)
+	return job, task
+}
+
+// SplitJobTask is the error returning counterpart of GetJobTask.
+func (d *StepDecoratorDef) SplitJobTask(s string) (string, string, error) {
+	// the step may be a namespaced reference, only split on the first slash
+	res := strings.SplitN(s, "/", 2)
+	if len(res) == 2 && res[1] != "" {
+		return res[0], res[1], nil
 	} else if len(res) == 1 {
-		return res[0], ""
-	} else {
-		log.Fatalf("error: invalid decorate target %s", s)
+		return res[0], "", nil
 	}
-	return res[0], res[1]
+	return "", "", fmt.Errorf("invalid decorate target %s", s)
 }
 
 func (d *StepDecoratorDef) String() string {
@@ -69,6 +94,7 @@ func (d *StepDecoratorDef) String() string {
 type Decorators struct {
 	Decorators []Decorator `yaml:"decorators"`
 	cache      map[string]Decorator
+	registry   *registry.Registry
 }
 
 func (d *Decorators) String() string {
@@ -77,6 +103,9 @@ func (d *Decorators) String() string {
 	return string(b[:])
 }
 
+// Populate instantiates the Decorator referenced by r. The Decorators of the
+// flat layout are looked up first, whatever their name; namespace/name@version
+// designates a Decorator of the registry otherwise.
 func (d *Decorators) Populate(r template.TemplateRef) (Decorator, error) {
 	if d.cache == nil {
 		d.cache = make(map[string]Decorator)
@@ -84,9 +113,23 @@ func (d *Decorators) Populate(r template.TemplateRef) (Decorator, error) {
 			d.cache[dd.Name] = dd
 		}
 	}
-	v, ok := d.cache[r.Name]
-	if !ok {
-		return Decorator{}, errors.New("no referenced Decorator definition")
+	if v, ok := d.cache[r.Name]; ok {
+		return v.Populate(r)
+	}
+	ref, err := registry.ParseRef(r.Name)
+	if err != nil || ref.IsLocal() {
+		return Decorator{}, fmt.Errorf("no referenced Decorator definition %s", r.Name)
+	}
+	if d.registry == nil {
+		return Decorator{}, fmt.Errorf("no registry to resolve Decorator %s", ref)
+	}
+	_, data, err := d.registry.Load(registry.DecoratorsKind, ref)
+	if err != nil {
+		return Decorator{}, err
+	}
+	v, err := getDecoratorFromString(data)
+	if err != nil {
+		return Decorator{}, err
 	}
 	return v.Populate(r)
 }
@@ -99,6 +142,9 @@ type Decorator struct {
 	job.StepHooks `yaml:",inline"`
 	// step modifier
 	job.StepModifiers `yaml:",inline"`
+	// Merge is the policy applied to the hooks and tags the decorated step
+	// already has, AppendMerge by default.
+	Merge string `yaml:"merge,omitempty"`
 }
 
 func (o *Decorator) Populate(r template.TemplateRef) (Decorator, error) {
@@ -123,6 +169,10 @@ func (o *Decorator) Populate(r template.TemplateRef) (Decorator, error) {
 	if err != nil {
 		return *o, err
 	}
+	o.OnError, err = o.Replace(r, o.OnError)
+	if err != nil {
+		return *o, err
+	}
 	o.OnAbort, err = o.Replace(r, o.OnAbort)
 	if err != nil {
 		return *o, err
@@ -148,85 +198,163 @@ func (o *Decorator) Populate(r template.TemplateRef) (Decorator, error) {
 	return *o, nil
 }
 
+// Decorate applies the hooks and modifiers of the decorator to step s, which
+// may be of any type, composite steps included. The step is returned as a map
+// so that fields typed steps do not know about are kept.
 func (d *Decorator) Decorate(s interface{}) (interface{}, error) {
-	ss, err := yaml.Marshal(&s)
+	err := d.checkMerge()
 	if err != nil {
 		return s, err
 	}
-	t, err := job.GetType(string(ss))
+	t, err := job.TypeOf(s)
 	if err != nil {
 		return s, err
 	}
-	switch t {
-	//	case job.GetStepType:
-	//		fallthrough
-	case job.PutStepType:
-		task, err := job.GetPutStep(s)
-		if err != nil {
-			return s, err
-		}
-		if d.OnSuccess != nil {
-			task.OnSuccess = d.OnSuccess
-		}
-		if d.OnFailure != nil {
-			task.OnFailure = d.OnFailure
-		}
-		if d.OnAbort != nil {
-			task.OnAbort = d.OnAbort
-		}
-		if d.Ensure != nil {
-			task.Ensure = d.Ensure
-		}
-		return task, nil
-	case job.TaskStepType:
-		//	case job.AggregateSteptype:
-		//		fallthrough
-		//	case job.DoStepType:
-		//		fallthrough
-		//	case job.TryStepType:
-		task, err := job.GetTaskStep(s)
-		if err != nil {
-			return s, err
+	if t == job.UnrecognizedType {
+		return s, errors.New(fmt.Sprintf("unsupported decorator type: %+v", t))
+	}
+	m, err := job.GetStepMap(s)
+	if err != nil {
+		return s, err
+	}
+	d.decorateHooks(m)
+	d.decorateModifiers(m)
+	return m, nil
+}
+
+// DecorateJob applies the hooks of the decorator to the ones of a job.
+func (d *Decorator) DecorateJob(h *job.StepHooks) error {
+	err := d.checkMerge()
+	if err != nil {
+		return err
+	}
+	h.OnSuccess = d.hook(h.OnSuccess, d.OnSuccess)
+	h.OnFailure = d.hook(h.OnFailure, d.OnFailure)
+	h.OnError = d.hook(h.OnError, d.OnError)
+	h.OnAbort = d.hook(h.OnAbort, d.OnAbort)
+	h.Ensure = d.hook(h.Ensure, d.Ensure)
+	return nil
+}
+
+func (d *Decorator) checkMerge() error {
+	switch d.Merge {
+	case "", AppendMerge, ReplaceMerge:
+		return nil
+	default:
+		return berror.Errorf("merge", "unsupported merge policy %s, expected %s or %s", d.Merge, AppendMerge, ReplaceMerge)
+	}
+}
+
+func (d *Decorator) decorateHooks(m map[interface{}]interface{}) {
+	hooks := []struct {
+		key  string
+		hook interface{}
+	}{
+		{"on_success", d.OnSuccess},
+		{"on_failure", d.OnFailure},
+		{"on_error", d.OnError},
+		{"on_abort", d.OnAbort},
+		{"ensure", d.Ensure},
+	}
+	for _, h := range hooks {
+		if h.hook != nil {
+			m[h.key] = d.hook(m[h.key], h.hook)
 		}
-		if d.OnSuccess != nil {
-			task.OnSuccess = d.OnSuccess
+	}
+}
+
+// hook returns the hook resulting from the decoration of hook existing with
+// hook h.
+func (d *Decorator) hook(existing, h interface{}) interface{} {
+	if h == nil {
+		return existing
+	}
+	if existing == nil || d.Merge == ReplaceMerge {
+		return h
+	}
+	if types.ValuesEqual(existing, h) {
+		return existing
+	}
+	// hooks already chained are extended rather than nested
+	if m, ok := existing.(map[interface{}]interface{}); ok && len(m) == 1 {
+		if steps, ok := m["do"].([]interface{}); ok {
+			for _, st := range steps {
+				if types.ValuesEqual(st, h) {
+					return existing
+				}
+			}
+			return map[interface{}]interface{}{"do": append(append([]interface{}{}, steps...), h)}
 		}
-		if d.OnFailure != nil {
-			task.OnFailure = d.OnFailure
+	}
+	return map[interface{}]interface{}{"do": []interface{}{existing, h}}
+}
+
+func (d *Decorator) decorateModifiers(m map[interface{}]interface{}) {
+	if len(d.Tags) != 0 {
+		var tags []interface{}
+		if existing, ok := m["tags"].([]interface{}); ok && d.Merge != ReplaceMerge {
+			tags = append(tags, existing...)
 		}
-		if d.OnAbort != nil {
-			task.OnAbort = d.OnAbort
+		for _, t := range d.Tags {
+			found := false
+			for _, e := range tags {
+				if e == t {
+					found = true
+					break
+				}
+			}
+			if !found {
+				tags = append(tags, t)
+			}
 		}
-		if d.Ensure != nil {
-			task.Ensure = d.Ensure
+		m["tags"] = tags
+	}
+	if d.Timeout != "" {
+		m["timeout"] = d.Timeout
+	}
+	if d.Attempts != "" {
+		// attempts is a string to be templated, concourse expects a number
+		if n, err := strconv.Atoi(d.Attempts); err == nil {
+			m["attempts"] = n
+		} else {
+			m["attempts"] = d.Attempts
 		}
-		return task, nil
-	default:
-		return s, errors.New(fmt.Sprintf("unsupported decorator type: %+v", t))
+	}
+	if len(d.Across) != 0 {
+		m["across"] = d.Across
 	}
 }
 
 func Decorate(s interface{}, descs ...Decorator) []interface{} {
+	res, err := ApplyDecorators(s, descs...)
+	berror.CheckError(err)
+	return res
+}
+
+// ApplyDecorators is the error returning counterpart of Decorate. Decorators
+// are applied in order to the step, which is emitted once, surrounded by
+// their before steps in order and their after steps in reverse order.
+func ApplyDecorators(s interface{}, descs ...Decorator) ([]interface{}, error) {
 	var res []interface{}
 	l := len(descs)
 	// added as interface{}
 	for i := 0; i < l; i++ {
 		res = append(res, descs[i].Before...)
 	}
+	step := s
 	for _, d := range descs {
-		ds, err := d.Decorate(s)
-		berror.CheckError(err)
-		res = append(res, ds)
+		var err error
+		step, err = d.Decorate(step)
+		if err != nil {
+			return res, berror.WithPath(err, fmt.Sprintf("decorators[%s]", d.Name))
+		}
 	}
+	res = append(res, step)
 	// added as interface{}
 	for i := l - 1; i >= 0; i-- {
 		res = append(res, descs[i].After...)
 	}
-	// if there is no decorator, add step
-	if l == 0 {
-		res = append(res, s)
-	}
-	return res
+	return res, nil
 }
 
 func (d *Decorator) String() string {
@@ -235,46 +363,82 @@ func (d *Decorator) String() string {
 	return string(b[:])
 }
 
-//TODO
+// Equal tells whether i is a Decorator of the same name, params and body.
 func (d Decorator) Equal(i interface{}) bool {
 	switch v := i.(type) {
 	case Decorator:
-		return false
+		return sameDefinition(d, v)
 	case *Decorator:
-		return false
-	default:
-		fmt.Printf("unsupported type %s\n", v)
+		return v != nil && sameDefinition(d, *v)
 	}
-	return true
+	return false
 }
 
 func GetDecoratorsFromString(data string) Decorators {
+	r, err := ParseDecorators(data)
+	berror.CheckError(err)
+	return r
+}
+
+// ParseDecorators is the error returning counterpart of GetDecoratorsFromString.
+func ParseDecorators(data string) (Decorators, error) {
 	r := Decorators{}
 
 	err := yaml.Unmarshal([]byte(data), &r)
-	berror.CheckError(err)
-	return r
+	if err != nil {
+		return r, berror.FromYAML(err)
+	}
+	return r, nil
+}
+
+func getDecoratorFromString(data string) (Decorator, error) {
+	r := Decorator{}
+	err := yaml.Unmarshal([]byte(data), &r)
+	if err != nil {
+		return r, berror.FromYAML(err)
+	}
+	return r, nil
+}
+
+// SaveDecoratorsLocally updates the decorators file of target with d, keeping
+// the comments, order and anchors of the decorators left unchanged.
+func SaveDecoratorsLocally(target string, d Decorators) error {
+	return yamlnode.UpdateFile(filepath.Join(target, decoratorsDir, decoratorsFile), d)
 }
 
 func GetLocalDecorators(target string) Decorators {
-	targetDir := filepath.Join(target, decoratorsDir)
-	ioutils.CreateDirIfNotExist(targetDir)
-	targetFile := filepath.Join(targetDir, decoratorsFile)
-	ioutils.CreateFileIfNotExist(targetFile)
-	content := ioutils.ReadFile(targetFile)
-	decs := GetDecoratorsFromString(content)
+	res, err := LoadLocalDecorators(target)
+	berror.CheckError(err)
+	return res
+}
+
+// LoadLocalDecorators is the error returning counterpart of GetLocalDecorators.
+func LoadLocalDecorators(target string) (Decorators, error) {
+	res := Decorators{registry: registry.New(target)}
+	targetFile := filepath.Join(target, decoratorsDir, decoratorsFile)
+	content, err := ioutils.LoadOrCreateFile(targetFile)
+	if err != nil {
+		return res, err
+	}
+	decs, err := ParseDecorators(content)
+	if err != nil {
+		return res, berror.WithFile(err, targetFile)
+	}
 	resSet := types.NewSet()
 	for _, d := range decs.Decorators {
 		resSet.Add(d)
 	}
-	res := Decorators{}
+	names := make(map[string]bool)
 	for _, d := range resSet.Get() {
-		switch v := d.(type) {
-		case Decorator:
-			res.Decorators = append(res.Decorators, v)
-		default:
-			fmt.Printf("unsupported type %s\n", v)
+		v, ok := d.(Decorator)
+		if !ok {
+			return res, berror.WithFile(fmt.Errorf("unsupported type %T", d), targetFile)
 		}
+		if names[v.Name] {
+			return res, berror.WithFile(berror.Errorf(fmt.Sprintf("decorators[%s]", v.Name), "Decorator defined twice with different bodies"), targetFile)
+		}
+		names[v.Name] = true
+		res.Decorators = append(res.Decorators, v)
 	}
-	return res
+	return res, nil
 }
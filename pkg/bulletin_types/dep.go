@@ -20,10 +20,20 @@ type Deps struct {
 }
 
 func (ds *Deps) SetDefault() Deps {
+	res, err := ds.setDefault()
+	berror.CheckError(err)
+	return res
+}
+
+func (ds *Deps) setDefault() (Deps, error) {
 	for i, d := range ds.Deps {
-		ds.Deps[i] = d.SetDefault()
+		nd, err := d.setDefault()
+		if err != nil {
+			return *ds, berror.WithPath(err, fmt.Sprintf("deps[%s]", d.Name))
+		}
+		ds.Deps[i] = nd
 	}
-	return *ds
+	return *ds, nil
 }
 
 func (ds *Deps) String() string {
@@ -32,22 +42,60 @@ func (ds *Deps) String() string {
 	return string(b[:])
 }
 
+// AddResourcesAs adds the gets of every dep of ds to jobs, see
+// Dep.AddResourceAs. With job.InParallelStepType, the aggregate steps of jobs
+// are migrated first so that gets join an in_parallel step heading a plan
+// rather than a second one.
+func (ds *Deps) AddResourcesAs(jobs job.Jobs, parallel job.Type) error {
+	if parallel == job.InParallelStepType {
+		err := jobs.MigrateAggregates()
+		if err != nil {
+			return err
+		}
+	}
+	for _, d := range ds.Deps {
+		err := d.AddResourceAs(jobs, parallel)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type Dep struct {
 	Name       string         `yaml:"name"`
 	RequiredBy []Requirements `yaml:"required_by"`
 }
 
-func (dep *Dep) AddResource(jobs job.Jobs) {
-	for _, r := range dep.RequiredBy {
-		r.AddResource(dep.Name, jobs)
+func (dep *Dep) AddResource(jobs job.Jobs) error {
+	return dep.AddResourceAs(jobs, job.AggregateStepType)
+}
+
+// AddResourceAs is AddResource grouping aggregatable gets in a step of type
+// parallel, job.AggregateStepType or job.InParallelStepType.
+func (dep *Dep) AddResourceAs(jobs job.Jobs, parallel job.Type) error {
+	for i, r := range dep.RequiredBy {
+		err := r.AddResourceAs(dep.Name, jobs, parallel)
+		if err != nil {
+			return berror.WithPath(err, fmt.Sprintf("deps[%s].required_by[%d]", dep.Name, i))
+		}
 	}
+	return nil
 }
 
 type Requirements []DepJobRef
 
 func (req *Requirements) AddResource(name string, jobs job.Jobs) error {
+	return req.AddResourceAs(name, jobs, job.AggregateStepType)
+}
+
+func (req *Requirements) AddResourceAs(name string, jobs job.Jobs, parallel job.Type) error {
 	for i, ref := range *req {
 		oldj, err := jobs.GetJob(ref.Name)
+		if err != nil {
+			return berror.Errorf(fmt.Sprintf("[%d]", i), "%s: %s", err, ref.Name)
+		}
+		err = oldj.BuildCache()
 		if err != nil {
 			return err
 		}
@@ -65,33 +113,23 @@ func (req *Requirements) AddResource(name string, jobs job.Jobs) error {
 		getStep := job.GetStep{
 			Get:     name,
 			Version: ref.Version,
-			Params:  ref.Params,
 			Trigger: ref.Trigger,
 		}
+		// a nil map held by the interface would be written as params: {}
+		if len(ref.Params) != 0 {
+			getStep.Params = ref.Params
+		}
 		if i >= 1 {
 			getStep.Passed = append(getStep.Passed, (*req)[i-1].Name)
 		}
 		if ref.aggregatableB {
-			aggregateSteps := oldj.GetStepsByType(job.AggregateStepType)
-			if len(aggregateSteps) == 0 {
-				aggregateStep := &job.AggregateStep{
-					Aggregate: []interface{}{
-						getStep,
-					},
-				}
-				oldj.Plan = append([]interface{}{aggregateStep}, oldj.Plan...)
-				oldj.AddStepByType(job.AggregateStepType, aggregateStep)
-			} else {
-				aggregateStepI := aggregateSteps[0]
-				switch v := aggregateStepI.(type) {
-				case *job.AggregateStep:
-					v.Aggregate = append(v.Aggregate, getStep)
-				default:
-					fmt.Printf("!!!\nerror: unsupported type %T\n", v)
-				}
+			err = addParallelGet(&oldj, parallel, getStep)
+			if err != nil {
+				return berror.WithPath(err, fmt.Sprintf("[%d]", i))
 			}
 		} else {
 			oldj.Plan = append(oldj.Plan, getStep)
+			oldj.ResetCache()
 		}
 		oldj.AddStepByTypeAndName(job.GetStepType, name, getStep)
 		err = jobs.UpdateJob(oldj)
@@ -102,14 +140,70 @@ func (req *Requirements) AddResource(name string, jobs job.Jobs) error {
 	return nil
 }
 
+// addParallelGet adds getStep to the step of type parallel heading the plan
+// of j, creating one when there is none. Parallel steps found further in the
+// plan run after the steps preceding them and are left alone.
+func addParallelGet(j *job.Job, parallel job.Type, getStep job.GetStep) error {
+	if len(j.Plan) != 0 {
+		p := j.Plan[0]
+		s, err := yaml.Marshal(&p)
+		if err != nil {
+			return err
+		}
+		t, _ := job.GetType(string(s))
+		if t == parallel {
+			switch parallel {
+			case job.AggregateStepType:
+				st, err := job.GetAggregateStep(p)
+				if err != nil {
+					return err
+				}
+				st.Aggregate = append(st.Aggregate, getStep)
+				j.Plan[0] = &st
+			case job.InParallelStepType:
+				st, err := job.GetInParallelStep(p)
+				if err != nil {
+					return err
+				}
+				st.InParallel.Steps = append(st.InParallel.Steps, getStep)
+				j.Plan[0] = &st
+			}
+			j.ResetCache()
+			return nil
+		}
+	}
+	var step interface{}
+	switch parallel {
+	case job.AggregateStepType:
+		step = &job.AggregateStep{Aggregate: []interface{}{getStep}}
+	case job.InParallelStepType:
+		step = &job.InParallelStep{InParallel: job.InParallelConfig{Steps: []interface{}{getStep}}}
+	default:
+		return fmt.Errorf("unsupported parallel step type %s", parallel)
+	}
+	j.Plan = append([]interface{}{step}, j.Plan...)
+	j.ResetCache()
+	return nil
+}
+
 func (d *Dep) SetDefault() Dep {
+	res, err := d.setDefault()
+	berror.CheckError(err)
+	return res
+}
+
+func (d *Dep) setDefault() (Dep, error) {
 	for i, a1 := range d.RequiredBy {
 		for j, a2 := range a1 {
-			a1[j] = a2.SetDefault()
+			ref, err := a2.setDefault()
+			if err != nil {
+				return *d, berror.WithPath(err, fmt.Sprintf("required_by[%d][%d]", i, j))
+			}
+			a1[j] = ref
 		}
 		d.RequiredBy[i] = a1
 	}
-	return *d
+	return *d, nil
 }
 
 func (d *Dep) String() string {
@@ -128,6 +222,12 @@ type DepJobRef struct {
 }
 
 func (d *DepJobRef) SetDefault() DepJobRef {
+	res, err := d.setDefault()
+	berror.CheckError(err)
+	return res
+}
+
+func (d *DepJobRef) setDefault() (DepJobRef, error) {
 	res := d
 	// no need to do this, concourse will handle it
 	//if res.Version == "" {
@@ -137,10 +237,12 @@ func (d *DepJobRef) SetDefault() DepJobRef {
 		res.aggregatableB = true
 	} else {
 		b, err := strconv.ParseBool(res.AggregatableString)
-		berror.CheckError(err)
+		if err != nil {
+			return *res, berror.WithPath(err, "aggregatable")
+		}
 		res.aggregatableB = b
 	}
-	return *res
+	return *res, nil
 }
 
 func (d *DepJobRef) String() string {
@@ -150,8 +252,17 @@ func (d *DepJobRef) String() string {
 }
 
 func GetDepsFromString(data string) Deps {
+	d, err := ParseDeps(data)
+	berror.CheckError(err)
+	return d
+}
+
+// ParseDeps is the error returning counterpart of GetDepsFromString.
+func ParseDeps(data string) (Deps, error) {
 	d := Deps{}
 	err := yaml.Unmarshal([]byte(data), &d)
-	berror.CheckError(err)
-	return d.SetDefault()
+	if err != nil {
+		return d, berror.FromYAML(err)
+	}
+	return d.setDefault()
 }
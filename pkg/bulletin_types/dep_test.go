@@ -0,0 +1,73 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"testing"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+)
+
+func TestDepsAddResourcesAs(t *testing.T) {
+	deps := `
+deps:
+- name: img
+  required_by:
+  - - name: build
+`
+	tests := []struct {
+		name     string
+		plan     string
+		parallel job.Type
+		want     string
+	}{
+		{
+			name:     "joins the aggregate heading the plan",
+			plan:     `[{aggregate: [{get: repo}]}, {task: unit}]`,
+			parallel: job.AggregateStepType,
+			want:     `[{aggregate: [{get: repo}, {get: img}]}, {task: unit}]`,
+		},
+		{
+			name:     "joins the aggregate heading the plan once migrated",
+			plan:     `[{aggregate: [{get: repo}]}, {task: unit}]`,
+			parallel: job.InParallelStepType,
+			want:     `[{in_parallel: [{get: repo}, {get: img}]}, {task: unit}]`,
+		},
+		{
+			name:     "parallel steps further in the plan are left alone",
+			plan:     `[{task: unit}, {aggregate: [{put: img1}, {put: img2}]}]`,
+			parallel: job.AggregateStepType,
+			want:     `[{aggregate: [{get: img}]}, {task: unit}, {aggregate: [{put: img1}, {put: img2}]}]`,
+		},
+		{
+			name:     "a parallel step is created at the head of the plan",
+			plan:     `[{task: unit}]`,
+			parallel: job.InParallelStepType,
+			want:     `[{in_parallel: [{get: img}]}, {task: unit}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, err := ParseDeps(deps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jobs, err := job.ParseJobs("jobs: [{name: build, plan: " + tt.plan + "}]")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ds.AddResourcesAs(jobs, tt.parallel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := job.ParseJobs("jobs: [{name: build, plan: " + tt.want + "}]")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameYAML(t, jobs, want)
+		})
+	}
+}
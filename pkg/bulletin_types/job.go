@@ -6,16 +6,21 @@ Sniperkit-Bot
 package bulletin_types
 
 import (
+	"fmt"
+
 	template "github.com/maplain/yamltemplate"
 	yaml "gopkg.in/yaml.v2"
 
 	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/group"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
 )
 
 type Jobs struct {
 	Jobs  []JobRef `yaml:"jobs"`
 	cache map[string]int
+	// groups of the jobs generated by matrices
+	groups group.Groups
 }
 
 func (j *Jobs) AddDecorator(job, task string, d template.TemplateRef) {
@@ -32,6 +37,21 @@ func (j *Jobs) AddDecorator(job, task string, d template.TemplateRef) {
 	j.Jobs[i].AddDecorator(task, d)
 }
 
+// AddSelectorDecorator applies decorator d to the jobs, or the steps of jobs,
+// designated by sel.
+func (j *Jobs) AddSelectorDecorator(sel Selector, d template.TemplateRef) {
+	for i := range j.Jobs {
+		if !sel.MatchJob(j.Jobs[i].Name) {
+			continue
+		}
+		if sel.SelectsJobs() {
+			j.Jobs[i].Decorators = append(j.Jobs[i].Decorators, d)
+			continue
+		}
+		j.Jobs[i].selectors = append(j.Jobs[i].selectors, selectorDecorator{sel, d})
+	}
+}
+
 func (j *Jobs) String() string {
 	b, err := yaml.Marshal(*j)
 	berror.CheckError(err)
@@ -39,18 +59,180 @@ func (j *Jobs) String() string {
 }
 
 func GetJobsFromString(data string) Jobs {
-	j := Jobs{}
-	err := yaml.Unmarshal([]byte(data), &j)
+	j, err := ParseJobs(data)
 	berror.CheckError(err)
 	return j
 }
 
+// ParseJobs is the error returning counterpart of GetJobsFromString.
+func ParseJobs(data string) (Jobs, error) {
+	j := Jobs{}
+	err := yaml.Unmarshal([]byte(data), &j)
+	if err != nil {
+		return j, berror.FromYAML(err)
+	}
+	return j, nil
+}
+
 type JobRef struct {
 	Plan          []StepRef `yaml:"plan"`
 	job.JobBase   `yaml:",inline"`
 	job.StepHooks `yaml:",inline"`
 	Decorators    []template.TemplateRef `yaml:"decorators,omitempty"`
-	cache         map[string]int
+	// Template references the JobTemplate the job instantiates, see
+	// Instantiate.
+	Template *template.TemplateRef `yaml:"template,omitempty"`
+	// Matrix instantiates Template once per combination of its values, see
+	// Jobs.Instantiate.
+	Matrix Matrix `yaml:"matrix,omitempty"`
+	cache  map[string]int
+	// decorators of the steps matching a selector, only known once expanded
+	selectors []selectorDecorator
+}
+
+type selectorDecorator struct {
+	Selector
+	Decorator template.TemplateRef
+}
+
+// Instantiate replaces the jobs instantiating a template with the job they
+// designate, see JobRef.Instantiate. Jobs with a matrix are replaced with one
+// job per combination of its values, named after the job and the values, and
+// gathered in a group named after the job, see Groups.
+func (j *Jobs) Instantiate(ts JobTemplates) error {
+	var res []JobRef
+	for _, jr := range j.Jobs {
+		name := jr.Name
+		if name == "" && jr.Template != nil {
+			name = jr.Template.Name
+		}
+		var jobs []JobRef
+		var err error
+		if len(jr.Matrix) == 0 {
+			var inst JobRef
+			inst, err = jr.Instantiate(ts)
+			jobs = append(jobs, inst)
+		} else {
+			jobs, err = jr.instantiateMatrix(ts)
+			if err == nil {
+				g := group.Group{Name: name}
+				for _, inst := range jobs {
+					g.Jobs = append(g.Jobs, inst.Name)
+				}
+				j.groups = j.groups.UpdateWith(group.Groups{Groups: []group.Group{g}})
+			}
+		}
+		if err != nil {
+			return berror.WithPath(err, fmt.Sprintf("jobs[%s]", name))
+		}
+		res = append(res, jobs...)
+	}
+	j.Jobs = res
+	j.cache = nil
+	return nil
+}
+
+// Groups returns the groups of the jobs generated by matrices.
+func (j *Jobs) Groups() group.Groups {
+	return j.groups
+}
+
+func (j *JobRef) instantiateMatrix(ts JobTemplates) ([]JobRef, error) {
+	if j.Template == nil {
+		return nil, berror.Errorf("matrix", "a matrix requires a job template")
+	}
+	combinations, err := j.Matrix.Combinations()
+	if err != nil {
+		return nil, berror.WithPath(err, "matrix")
+	}
+	var res []JobRef
+	seen := make(map[string]bool)
+	unique := true
+	for _, c := range combinations {
+		inst := *j
+		inst.Matrix = nil
+		ref := withParams(*j.Template, c)
+		inst.Template = &ref
+		if j.Name != "" {
+			inst.Name = j.Name + "-" + j.Matrix.Suffix(c)
+		}
+		r, err := inst.Instantiate(ts)
+		if err != nil {
+			return nil, err
+		}
+		unique = unique && !seen[r.Name]
+		seen[r.Name] = true
+		res = append(res, r)
+	}
+	// names of the template not depending on the matrix are suffixed
+	if !unique {
+		for i, c := range combinations {
+			res[i].Name += "-" + j.Matrix.Suffix(c)
+		}
+	}
+	return res, nil
+}
+
+// Instantiate returns the job designated by j when it references a template:
+// the template populated with the params of the reference, named after j.
+// Steps and decorators of j are added to the ones of the template, and fields
+// set on j override the ones of the template.
+func (j *JobRef) Instantiate(ts JobTemplates) (JobRef, error) {
+	if j.Template == nil {
+		return *j, nil
+	}
+	res, err := ts.Populate(*j.Template)
+	if err != nil {
+		return res, berror.WithPath(err, fmt.Sprintf("template[%s]", j.Template.Name))
+	}
+	if res.Template != nil {
+		return res, berror.Errorf(fmt.Sprintf("template[%s]", j.Template.Name), "templates can not instantiate other templates")
+	}
+	if j.Name != "" {
+		res.Name = j.Name
+	}
+	if res.Name == "" {
+		return res, berror.Errorf("name", "no name for the job instantiating template %s", j.Template.Name)
+	}
+	if j.Serial {
+		res.Serial = true
+	}
+	if j.BuildLogsToRetain != 0 {
+		res.BuildLogsToRetain = j.BuildLogsToRetain
+	}
+	if len(j.SerialGroups) != 0 {
+		res.SerialGroups = j.SerialGroups
+	}
+	if j.MaxInFlight != 0 {
+		res.MaxInFlight = j.MaxInFlight
+	}
+	if j.Public {
+		res.Public = true
+	}
+	if j.DisableManualTrigger {
+		res.DisableManualTrigger = true
+	}
+	if j.Interruptible {
+		res.Interruptible = true
+	}
+	if j.OnSuccess != nil {
+		res.OnSuccess = j.OnSuccess
+	}
+	if j.OnFailure != nil {
+		res.OnFailure = j.OnFailure
+	}
+	if j.OnError != nil {
+		res.OnError = j.OnError
+	}
+	if j.OnAbort != nil {
+		res.OnAbort = j.OnAbort
+	}
+	if j.Ensure != nil {
+		res.Ensure = j.Ensure
+	}
+	res.Plan = append(res.Plan, j.Plan...)
+	res.Decorators = append(res.Decorators, j.Decorators...)
+	return res, nil
 }
 
 func (j *JobRef) String() string {
@@ -85,6 +267,9 @@ func (j *JobRef) AddDecorator(task string, d template.TemplateRef) {
 type StepRef struct {
 	template.TemplateRef `yaml:",inline"`
 	Decorators           []template.TemplateRef `yaml:"decorators,omitempty"`
+	// Matrix populates the step once per combination of its values, the
+	// steps following each other in the plan.
+	Matrix Matrix `yaml:"matrix,omitempty"`
 }
 
 func (j *StepRef) String() string {
@@ -94,33 +279,91 @@ func (j *StepRef) String() string {
 }
 
 func (s *StepRef) DeRef(decs Decorators, ss Steps) ([]interface{}, error) {
+	return s.deRef(decs, ss, nil)
+}
+
+// deRef dereferences the step along with its decorators and the ones of the
+// selectors matching it.
+func (s *StepRef) deRef(decs Decorators, ss Steps, selectors []selectorDecorator) ([]interface{}, error) {
 	var res []interface{}
+	if len(s.Matrix) != 0 {
+		combinations, err := s.Matrix.Combinations()
+		if err != nil {
+			return res, berror.WithPath(err, "matrix")
+		}
+		for _, c := range combinations {
+			inst := *s
+			inst.Matrix = nil
+			inst.TemplateRef = withParams(s.TemplateRef, c)
+			st, err := inst.deRef(decs, ss, selectors)
+			if err != nil {
+				return res, berror.WithPath(err, fmt.Sprintf("matrix[%s]", s.Matrix.Suffix(c)))
+			}
+			res = append(res, st...)
+		}
+		return res, nil
+	}
 	step, err := ss.Populate(s.TemplateRef)
 	if err != nil {
 		return res, err
 	}
 	i := step.GetStep()
+	refs := s.Decorators
+	if len(selectors) != 0 {
+		t, err := job.TypeOf(i)
+		if err != nil {
+			return res, err
+		}
+		// composite steps have no name
+		name, _ := job.GetStepName(i)
+		for _, sd := range selectors {
+			if sd.MatchStep(t, s.Name, name) {
+				refs = append(refs, sd.Decorator)
+			}
+		}
+	}
 	var ds []Decorator
-	for _, d := range s.Decorators {
+	for _, d := range refs {
 		dec, err := decs.Populate(d)
 		if err != nil {
 			return res, err
 		}
 		ds = append(ds, dec)
 	}
-	return Decorate(i, ds...), nil
+	return ApplyDecorators(i, ds...)
 }
 
 func (jobs *Jobs) Convert(decs Decorators, ss Steps) job.Jobs {
+	res, err := jobs.Expand(decs, ss)
+	berror.CheckError(err)
+	return res
+}
+
+// Expand is the error returning counterpart of Convert.
+func (jobs *Jobs) Expand(decs Decorators, ss Steps) (job.Jobs, error) {
 	res := job.Jobs{}
 	for _, j := range jobs.Jobs {
-		res.Jobs = append(res.Jobs, j.Convert(decs, ss))
+		cj, err := j.Expand(decs, ss)
+		if err != nil {
+			return res, berror.WithPath(err, fmt.Sprintf("jobs[%s]", j.Name))
+		}
+		res.Jobs = append(res.Jobs, cj)
 	}
-	return res
+	return res, nil
 }
 
 func (j *JobRef) Convert(decs Decorators, ss Steps) job.Job {
+	res, err := j.Expand(decs, ss)
+	berror.CheckError(err)
+	return res
+}
+
+// Expand is the error returning counterpart of Convert.
+func (j *JobRef) Expand(decs Decorators, ss Steps) (job.Job, error) {
 	res := job.Job{}
+	if j.Template != nil {
+		return res, berror.Errorf(fmt.Sprintf("template[%s]", j.Template.Name), "job template is not instantiated")
+	}
 	// copy job base
 	res.Name = j.Name
 	res.Serial = j.Serial
@@ -132,44 +375,30 @@ func (j *JobRef) Convert(decs Decorators, ss Steps) job.Job {
 	res.Interruptible = j.Interruptible
 
 	// dereference step refs
-	for _, sref := range j.Plan {
+	for i, sref := range j.Plan {
+		path := fmt.Sprintf("plan[%d]", i)
 		// get real step
-		st, err := sref.DeRef(decs, ss)
-		berror.CheckError(err)
-		// aggregate step is the first step
-		for _, step := range st {
-			b, err := yaml.Marshal(step)
-			berror.CheckError(err)
-			t, err := job.GetType(string(b[:]))
-			berror.CheckError(err)
-			switch t {
-			case job.AggregateStepType:
-				steps, err := job.GetAggregateStep(step)
-				berror.CheckError(err)
-				res.Plan = append([]interface{}{&steps}, res.Plan...)
-			default:
-				res.Plan = append(res.Plan, step)
-			}
+		st, err := sref.deRef(decs, ss, j.selectors)
+		if err != nil {
+			return res, berror.WithPath(err, path)
 		}
+		// steps keep their place in the plan, the parallel step gathering
+		// the gets of deps being the only one added at its head
+		res.Plan = append(res.Plan, st...)
 	}
 
 	// dereference job decorators
+	res.StepHooks = j.StepHooks
 	for _, dref := range j.Decorators {
 		d, err := decs.Populate(dref)
-		berror.CheckError(err)
-		if d.OnSuccess != nil {
-			res.OnSuccess = d.OnSuccess
-		}
-		if d.OnFailure != nil {
-			res.OnFailure = d.OnFailure
-		}
-		if d.OnAbort != nil {
-			res.OnAbort = d.OnAbort
+		if err != nil {
+			return res, berror.WithPath(err, fmt.Sprintf("decorators[%s]", dref.Name))
 		}
-		if d.Ensure != nil {
-			res.Ensure = d.Ensure
+		err = d.DecorateJob(&res.StepHooks)
+		if err != nil {
+			return res, berror.WithPath(err, fmt.Sprintf("decorators[%s]", dref.Name))
 		}
 	}
 
-	return res
+	return res, nil
 }
@@ -0,0 +1,170 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"fmt"
+	"path/filepath"
+
+	template "github.com/maplain/yamltemplate"
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/registry"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+const (
+	jobTemplatesDir  = "jobs"
+	jobTemplatesFile = "jobs.yml"
+)
+
+type JobTemplates struct {
+	Jobs     []JobTemplate `yaml:"jobs"`
+	cache    map[string]JobTemplate
+	registry *registry.Registry
+}
+
+func (j *JobTemplates) String() string {
+	b, err := yaml.Marshal(*j)
+	berror.CheckError(err)
+	return string(b[:])
+}
+
+// Populate instantiates the JobTemplate referenced by r. The JobTemplates of
+// the flat layout are looked up first, whatever their name;
+// namespace/name@version designates a JobTemplate of the registry otherwise.
+func (j *JobTemplates) Populate(r template.TemplateRef) (JobRef, error) {
+	if j.cache == nil {
+		j.cache = make(map[string]JobTemplate)
+		for _, jt := range j.Jobs {
+			j.cache[jt.Name] = jt
+		}
+	}
+	if v, ok := j.cache[r.Name]; ok {
+		return v.Populate(r)
+	}
+	ref, err := registry.ParseRef(r.Name)
+	if err != nil || ref.IsLocal() {
+		return JobRef{}, fmt.Errorf("no referenced JobTemplate definition %s", r.Name)
+	}
+	if j.registry == nil {
+		return JobRef{}, fmt.Errorf("no registry to resolve JobTemplate %s", ref)
+	}
+	_, data, err := j.registry.Load(registry.JobsKind, ref)
+	if err != nil {
+		return JobRef{}, err
+	}
+	v, err := getJobTemplateFromString(data)
+	if err != nil {
+		return JobRef{}, err
+	}
+	return v.Populate(r)
+}
+
+// JobTemplate is a parameterized job, written like the jobs of a bulletin
+// pipeline: its plan references steps and it may be decorated.
+type JobTemplate struct {
+	template.TemplateDef `yaml:",inline"`
+	Job                  interface{} `yaml:"job"`
+}
+
+func (j *JobTemplate) String() string {
+	b, err := yaml.Marshal(*j)
+	berror.CheckError(err)
+	return string(b[:])
+}
+
+func (j *JobTemplate) Populate(r template.TemplateRef) (JobRef, error) {
+	res := JobRef{}
+	v, err := j.Replace(r, j.Job)
+	if err != nil {
+		return res, err
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return res, err
+	}
+	err = yaml.Unmarshal(b, &res)
+	if err != nil {
+		return res, berror.FromYAMLFragment(err)
+	}
+	return res, nil
+}
+
+// Equal tells whether i is a JobTemplate of the same name, params and body.
+func (j JobTemplate) Equal(i interface{}) bool {
+	switch v := i.(type) {
+	case JobTemplate:
+		return sameDefinition(j, v)
+	case *JobTemplate:
+		return v != nil && sameDefinition(j, *v)
+	}
+	return false
+}
+
+func GetJobTemplatesFromString(data string) JobTemplates {
+	r, err := ParseJobTemplates(data)
+	berror.CheckError(err)
+	return r
+}
+
+// ParseJobTemplates is the error returning counterpart of GetJobTemplatesFromString.
+func ParseJobTemplates(data string) (JobTemplates, error) {
+	r := JobTemplates{}
+	err := yaml.Unmarshal([]byte(data), &r)
+	if err != nil {
+		return r, berror.FromYAML(err)
+	}
+	return r, nil
+}
+
+func getJobTemplateFromString(data string) (JobTemplate, error) {
+	r := JobTemplate{}
+	err := yaml.Unmarshal([]byte(data), &r)
+	if err != nil {
+		return r, berror.FromYAML(err)
+	}
+	return r, nil
+}
+
+func GetLocalJobTemplates(target string) JobTemplates {
+	res, err := LoadLocalJobTemplates(target)
+	berror.CheckError(err)
+	return res
+}
+
+// LoadLocalJobTemplates is the error returning counterpart of GetLocalJobTemplates.
+func LoadLocalJobTemplates(target string) (JobTemplates, error) {
+	res := JobTemplates{registry: registry.New(target)}
+	targetFile := filepath.Join(target, jobTemplatesDir, jobTemplatesFile)
+	content, err := ioutils.LoadOrCreateFile(targetFile)
+	if err != nil {
+		return res, err
+	}
+	jobs, err := ParseJobTemplates(content)
+	if err != nil {
+		return res, berror.WithFile(err, targetFile)
+	}
+	resSet := types.NewSet()
+	for _, d := range jobs.Jobs {
+		resSet.Add(d)
+	}
+	names := make(map[string]bool)
+	for _, d := range resSet.Get() {
+		v, ok := d.(JobTemplate)
+		if !ok {
+			return res, berror.WithFile(fmt.Errorf("unsupported type %T", d), targetFile)
+		}
+		if names[v.Name] {
+			return res, berror.WithFile(berror.Errorf(fmt.Sprintf("jobs[%s]", v.Name), "JobTemplate defined twice with different bodies"), targetFile)
+		}
+		names[v.Name] = true
+		res.Jobs = append(res.Jobs, v)
+	}
+	return res, nil
+}
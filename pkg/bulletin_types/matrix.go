@@ -0,0 +1,81 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	template "github.com/maplain/yamltemplate"
+)
+
+// Matrix fans a reference out into one instance per combination of the
+// values of its axes, every axis being a template param, e.g.
+//
+//	matrix:
+//	  go: ["1.20", "1.21"]
+//	  os: [linux, windows]
+//
+// Values are decoded as strings so that 1.20 is not read as 1.2.
+type Matrix map[string][]string
+
+// Axes returns the names of the axes, sorted.
+func (m Matrix) Axes() []string {
+	var res []string
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Combinations returns the cartesian product of the values of the axes, in
+// the order of the axes and of their values, the last axis varying first.
+func (m Matrix) Combinations() ([]map[string]string, error) {
+	res := []map[string]string{{}}
+	for _, a := range m.Axes() {
+		if len(m[a]) == 0 {
+			return nil, fmt.Errorf("matrix axis %s has no value", a)
+		}
+		var next []map[string]string
+		for _, c := range res {
+			for _, v := range m[a] {
+				n := map[string]string{a: v}
+				for k, cv := range c {
+					n[k] = cv
+				}
+				next = append(next, n)
+			}
+		}
+		res = next
+	}
+	return res, nil
+}
+
+// Suffix returns the values of combination c joined in the order of the axes,
+// e.g. "1.20-linux", to name the instance of c.
+func (m Matrix) Suffix(c map[string]string) string {
+	var res []string
+	for _, a := range m.Axes() {
+		res = append(res, c[a])
+	}
+	return strings.Join(res, "-")
+}
+
+// withParams returns a copy of r whose params are completed with the values
+// of combination c.
+func withParams(r template.TemplateRef, c map[string]string) template.TemplateRef {
+	params := make(map[string]interface{})
+	for k, v := range r.Params {
+		params[k] = v
+	}
+	for k, v := range c {
+		params[k] = v
+	}
+	r.Params = params
+	return r
+}
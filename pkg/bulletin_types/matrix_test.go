@@ -0,0 +1,74 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatrixCombinations(t *testing.T) {
+	tests := []struct {
+		name     string
+		matrix   Matrix
+		want     []map[string]string
+		suffixes []string
+		err      bool
+	}{
+		{
+			name:     "single axis",
+			matrix:   Matrix{"go": {"1.20", "1.21"}},
+			want:     []map[string]string{{"go": "1.20"}, {"go": "1.21"}},
+			suffixes: []string{"1.20", "1.21"},
+		},
+		{
+			name:   "axes sorted by name, the last varying first",
+			matrix: Matrix{"os": {"linux", "windows"}, "go": {"1.20", "1.21"}},
+			want: []map[string]string{
+				{"go": "1.20", "os": "linux"},
+				{"go": "1.20", "os": "windows"},
+				{"go": "1.21", "os": "linux"},
+				{"go": "1.21", "os": "windows"},
+			},
+			suffixes: []string{"1.20-linux", "1.20-windows", "1.21-linux", "1.21-windows"},
+		},
+		{
+			name:     "no axis",
+			matrix:   Matrix{},
+			want:     []map[string]string{{}},
+			suffixes: []string{""},
+		},
+		{
+			name:   "axis without value",
+			matrix: Matrix{"go": {"1.20"}, "os": {}},
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.matrix.Combinations()
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			var suffixes []string
+			for _, c := range got {
+				suffixes = append(suffixes, tt.matrix.Suffix(c))
+			}
+			if !reflect.DeepEqual(suffixes, tt.suffixes) {
+				t.Errorf("got suffixes %q, want %q", suffixes, tt.suffixes)
+			}
+		})
+	}
+}
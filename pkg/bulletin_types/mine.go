@@ -0,0 +1,605 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	template "github.com/maplain/yamltemplate"
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/resource"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+// DefaultMinOccurrences is the number of times a step or a hook is found by
+// default before Mine shares it as a template.
+const DefaultMinOccurrences = 2
+
+var (
+	templateNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+	paramNameRegexp    = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+	minedHooks = []string{"on_success", "on_failure", "on_error", "on_abort", "ensure"}
+)
+
+type MineOptions struct {
+	// MinOccurrences is the number of times a get, put or task step, or a
+	// hook, must be found to become a shared template.
+	MinOccurrences int
+	// Steps and Decorators already defined: equal templates are reused, and
+	// the names of the others are not taken.
+	Steps      Steps
+	Decorators Decorators
+}
+
+// Mined holds the templates mined from the jobs of a pipeline, along with the
+// jobs rewritten to reference them. Steps and Decorators only hold the
+// templates missing from the ones given to Mine.
+type Mined struct {
+	Steps      Steps
+	Decorators Decorators
+	Jobs       Jobs
+}
+
+// occurrence is a step, or a hook, found in the jobs.
+type occurrence struct {
+	job int
+	// index of the step in the plan, -1 for the hooks of the job
+	step int
+	// hook key, empty for steps
+	hook  string
+	value interface{}
+	ref   template.TemplateRef
+}
+
+// cluster gathers occurrences of the same step, differing only by the values
+// Mine lifts into template params.
+type cluster struct {
+	key         string
+	occurrences []*occurrence
+}
+
+type miner struct {
+	opts  MineOptions
+	names map[string]bool
+	res   Mined
+}
+
+// Mine turns jobs into bulletin jobs: hooks found at least MinOccurrences
+// times, e.g. on_failure puts to a chat resource, become Decorators, and so
+// do get, put and task steps, the other steps being kept as templates used
+// once. Values differing between the occurrences of a template are lifted
+// into its params, so that expanding the jobs gives them back.
+func Mine(jobs job.Jobs, opts MineOptions) (Mined, error) {
+	if opts.MinOccurrences <= 0 {
+		opts.MinOccurrences = DefaultMinOccurrences
+	}
+	m := &miner{opts: opts, names: make(map[string]bool)}
+	for _, s := range opts.Steps.Steps {
+		m.names[s.Name] = true
+	}
+	for _, d := range opts.Decorators.Decorators {
+		m.names[d.Name] = true
+	}
+
+	// steps as maps, their mined hooks being removed
+	plans := make([][]map[interface{}]interface{}, len(jobs.Jobs))
+	var hooks []*occurrence
+	for i, j := range jobs.Jobs {
+		for k, s := range j.Plan {
+			sm, err := job.GetStepMap(s)
+			if err != nil {
+				return m.res, berror.WithPath(err, fmt.Sprintf("jobs[%s].plan[%d]", j.Name, k))
+			}
+			plans[i] = append(plans[i], sm)
+			for _, h := range minedHooks {
+				if v, ok := sm[h]; ok && v != nil {
+					hooks = append(hooks, &occurrence{job: i, step: k, hook: h, value: v})
+				}
+			}
+		}
+		jh := map[string]interface{}{
+			"on_success": j.OnSuccess,
+			"on_failure": j.OnFailure,
+			"on_error":   j.OnError,
+			"on_abort":   j.OnAbort,
+			"ensure":     j.Ensure,
+		}
+		for _, h := range minedHooks {
+			if jh[h] == nil {
+				continue
+			}
+			v, err := job.GetStepMap(jh[h])
+			if err != nil {
+				return m.res, berror.WithPath(err, fmt.Sprintf("jobs[%s].%s", j.Name, h))
+			}
+			hooks = append(hooks, &occurrence{job: i, step: -1, hook: h, value: v})
+		}
+	}
+
+	decorated := make(map[*occurrence]bool)
+	for _, c := range clusters(hooks) {
+		if len(c.occurrences) < opts.MinOccurrences {
+			continue
+		}
+		first := c.occurrences[0]
+		body, params := lift(c.occurrences)
+		name := strings.Replace(first.hook, "_", "-", -1) + "-" + stepLabel(first.value)
+		d := Decorator{TemplateDef: template.TemplateDef{Params: params}}
+		switch first.hook {
+		case "on_success":
+			d.OnSuccess = body
+		case "on_failure":
+			d.OnFailure = body
+		case "on_error":
+			d.OnError = body
+		case "on_abort":
+			d.OnAbort = body
+		case "ensure":
+			d.Ensure = body
+		}
+		d.Name = m.decoratorName(name, d)
+		for _, o := range c.occurrences {
+			o.ref.Name = d.Name
+			decorated[o] = true
+			if o.step >= 0 {
+				delete(plans[o.job][o.step], o.hook)
+			}
+		}
+	}
+
+	var steps []*occurrence
+	for i := range jobs.Jobs {
+		for k, sm := range plans[i] {
+			steps = append(steps, &occurrence{job: i, step: k, value: sm})
+		}
+	}
+	for _, c := range clusters(steps) {
+		first := c.occurrences[0]
+		t, err := job.TypeOf(first.value)
+		if err != nil {
+			return m.res, err
+		}
+		shared := len(c.occurrences) >= opts.MinOccurrences
+		switch t {
+		case job.GetStepType, job.PutStepType, job.TaskStepType:
+		default:
+			shared = false
+		}
+		if shared {
+			body, params := lift(c.occurrences)
+			s := Step{TemplateDef: template.TemplateDef{Params: params}, Step: body}
+			s.Name = m.stepName(stepLabel(first.value), s)
+			for _, o := range c.occurrences {
+				o.ref.Name = s.Name
+			}
+			continue
+		}
+		for _, o := range c.occurrences {
+			label := stepLabel(o.value)
+			if name, err := job.GetStepName(o.value); err != nil || name == "" {
+				label = fmt.Sprintf("plan-%d", o.step)
+			}
+			s := Step{Step: o.value}
+			s.Name = m.stepName(jobs.Jobs[o.job].Name+"-"+label, s)
+			o.ref.Name = s.Name
+		}
+	}
+
+	for _, j := range jobs.Jobs {
+		jr := JobRef{JobBase: j.JobBase, StepHooks: j.StepHooks}
+		jr.Plan = make([]StepRef, len(j.Plan))
+		m.res.Jobs.Jobs = append(m.res.Jobs.Jobs, jr)
+	}
+	for _, o := range steps {
+		m.res.Jobs.Jobs[o.job].Plan[o.step].TemplateRef = o.ref
+	}
+	for _, o := range hooks {
+		if !decorated[o] {
+			continue
+		}
+		jr := &m.res.Jobs.Jobs[o.job]
+		if o.step >= 0 {
+			jr.Plan[o.step].Decorators = append(jr.Plan[o.step].Decorators, o.ref)
+			continue
+		}
+		jr.Decorators = append(jr.Decorators, o.ref)
+		switch o.hook {
+		case "on_success":
+			jr.OnSuccess = nil
+		case "on_failure":
+			jr.OnFailure = nil
+		case "on_error":
+			jr.OnError = nil
+		case "on_abort":
+			jr.OnAbort = nil
+		case "ensure":
+			jr.Ensure = nil
+		}
+	}
+	return m.res, nil
+}
+
+// Redact returns m with the credentials found in plain text in its steps,
+// decorators and jobs replaced by ((var)) references, named after the kind
+// and the name of the template or job holding them, e.g.
+// ((steps_deploy_params_password)), along with the secrets replaced. Params
+// lifted out of templates are redacted in the references of the jobs.
+func (m *Mined) Redact() (Mined, []resource.Secret, error) {
+	res := Mined{}
+	var secrets []resource.Secret
+	redact := func(path, prefix string, v interface{}) (interface{}, error) {
+		r, s, err := resource.RedactValue(path, prefix, v)
+		if err != nil {
+			return v, berror.WithPath(err, path)
+		}
+		secrets = append(secrets, s...)
+		return r, nil
+	}
+	redactHooks := func(path, prefix string, h *job.StepHooks) error {
+		hooks := []*interface{}{&h.OnSuccess, &h.OnFailure, &h.OnError, &h.OnAbort, &h.Ensure}
+		for i, key := range minedHooks {
+			v, err := redact(path+"."+key, prefix+"_"+key, *hooks[i])
+			if err != nil {
+				return err
+			}
+			*hooks[i] = v
+		}
+		return nil
+	}
+	redactRef := func(path, prefix string, r *template.TemplateRef) error {
+		if len(r.Params) == 0 {
+			return nil
+		}
+		params := make(map[interface{}]interface{})
+		for k, v := range r.Params {
+			params[k] = v
+		}
+		v, err := redact(path+".params", prefix, params)
+		if err != nil {
+			return err
+		}
+		r.Params = make(map[string]interface{})
+		for k, e := range v.(map[interface{}]interface{}) {
+			r.Params[fmt.Sprint(k)] = e
+		}
+		return nil
+	}
+
+	for _, st := range m.Steps.Steps {
+		v, err := redact(fmt.Sprintf("steps[%s].step", st.Name), "steps_"+st.Name, st.Step)
+		if err != nil {
+			return res, secrets, err
+		}
+		st.Step = v
+		res.Steps.Steps = append(res.Steps.Steps, st)
+	}
+	for _, d := range m.Decorators.Decorators {
+		err := redactHooks(fmt.Sprintf("decorators[%s]", d.Name), "decorators_"+d.Name, &d.StepHooks)
+		if err != nil {
+			return res, secrets, err
+		}
+		res.Decorators.Decorators = append(res.Decorators.Decorators, d)
+	}
+	for _, j := range m.Jobs.Jobs {
+		path, prefix := fmt.Sprintf("jobs[%s]", j.Name), "jobs_"+j.Name
+		err := redactHooks(path, prefix, &j.StepHooks)
+		if err != nil {
+			return res, secrets, err
+		}
+		plan := make([]StepRef, len(j.Plan))
+		for i, sr := range j.Plan {
+			spath, sprefix := fmt.Sprintf("%s.plan[%d]", path, i), fmt.Sprintf("%s_%d", prefix, i)
+			err := redactRef(spath, sprefix, &sr.TemplateRef)
+			if err != nil {
+				return res, secrets, err
+			}
+			decs := make([]template.TemplateRef, len(sr.Decorators))
+			for k, d := range sr.Decorators {
+				err := redactRef(fmt.Sprintf("%s.decorators[%d]", spath, k), fmt.Sprintf("%s_%s", sprefix, d.Name), &d)
+				if err != nil {
+					return res, secrets, err
+				}
+				decs[k] = d
+			}
+			if sr.Decorators != nil {
+				sr.Decorators = decs
+			}
+			plan[i] = sr
+		}
+		j.Plan = plan
+		decs := make([]template.TemplateRef, len(j.Decorators))
+		for k, d := range j.Decorators {
+			err := redactRef(fmt.Sprintf("%s.decorators[%d]", path, k), prefix+"_"+d.Name, &d)
+			if err != nil {
+				return res, secrets, err
+			}
+			decs[k] = d
+		}
+		if j.Decorators != nil {
+			j.Decorators = decs
+		}
+		res.Jobs.Jobs = append(res.Jobs.Jobs, j)
+	}
+	return res, secrets, nil
+}
+
+// clusters groups occurrences of the same hook and step, e.g. puts to the
+// same resource or tasks running the same file, whose values only differ by
+// liftable strings. Clusters keep the order of their first occurrence.
+func clusters(occurrences []*occurrence) []*cluster {
+	var res []*cluster
+	index := make(map[string]*cluster)
+	for _, o := range occurrences {
+		b, err := yaml.Marshal(shape(o.value))
+		if err != nil {
+			b = []byte(fmt.Sprintf("%p", o))
+		}
+		key := o.hook + "\n" + identity(o.value) + "\n" + string(b)
+		c, ok := index[key]
+		if !ok {
+			c = &cluster{key: key}
+			index[key] = c
+			res = append(res, c)
+		}
+		c.occurrences = append(c.occurrences, o)
+	}
+	return res
+}
+
+// identity tells what a step works on: the resource of gets and puts, the
+// file, or the name, of tasks.
+func identity(s interface{}) string {
+	t, err := job.TypeOf(s)
+	if err != nil {
+		return ""
+	}
+	switch t {
+	case job.TaskStepType:
+		if st, err := job.GetTaskStep(s); err == nil && st.File != "" {
+			return t.String() + " " + st.File
+		}
+	case job.GetStepType:
+		if st, err := job.GetGetStep(s); err == nil {
+			return t.String() + " " + st.ResourceName()
+		}
+	case job.PutStepType:
+		if st, err := job.GetPutStep(s); err == nil {
+			return t.String() + " " + st.ResourceName()
+		}
+	}
+	name, _ := job.GetStepName(s)
+	return t.String() + " " + name
+}
+
+// stepLabel names a template after the step it holds, e.g. "put-slack" or
+// "task-unit" for a task running ci/unit.yml.
+func stepLabel(s interface{}) string {
+	t, err := job.TypeOf(s)
+	if err != nil {
+		return "step"
+	}
+	name, _ := job.GetStepName(s)
+	if t == job.TaskStepType {
+		if st, err := job.GetTaskStep(s); err == nil && st.File != "" {
+			name = strings.TrimSuffix(path.Base(st.File), path.Ext(st.File))
+		}
+	}
+	if name == "" {
+		return t.String()
+	}
+	return t.String() + "-" + name
+}
+
+// liftable tells whether v can become a template param: a string written as
+// is in yaml, so that replacing the param with it gives v back.
+func liftable(v interface{}) bool {
+	s, ok := v.(string)
+	if !ok || s == "" || strings.Contains(s, "((") {
+		return false
+	}
+	b, err := yaml.Marshal(s)
+	return err == nil && strings.TrimSuffix(string(b), "\n") == s
+}
+
+// shape returns v with its liftable values blanked.
+func shape(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{})
+		for k, e := range vv {
+			res[k] = shape(e)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(vv))
+		for i, e := range vv {
+			res[i] = shape(e)
+		}
+		return res
+	}
+	if liftable(v) {
+		return ""
+	}
+	return v
+}
+
+// lift returns the value of the first occurrence with the values differing
+// between occurrences replaced by ((param)) placeholders, along with the
+// params. The refs of the occurrences are given their values.
+func lift(occurrences []*occurrence) (interface{}, []string) {
+	values := make(map[string][]interface{})
+	var paths []string
+	for _, o := range occurrences {
+		leaves("", o.value, func(p string, v interface{}) {
+			if _, ok := values[p]; !ok {
+				paths = append(paths, p)
+			}
+			values[p] = append(values[p], v)
+		})
+	}
+	sort.Strings(paths)
+
+	lifted := make(map[string]string)
+	used := make(map[string]bool)
+	var params []string
+	for _, p := range paths {
+		vs := values[p]
+		differ := false
+		for _, v := range vs[1:] {
+			if !types.ValuesEqual(v, vs[0]) {
+				differ = true
+				break
+			}
+		}
+		if !differ || !liftable(vs[0]) {
+			continue
+		}
+		name := paramName(p, true)
+		if used[name] {
+			name = paramName(p, false)
+		}
+		used[name] = true
+		lifted[p] = name
+		params = append(params, name)
+	}
+	for _, o := range occurrences {
+		o.ref.Params = nil
+		leaves("", o.value, func(p string, v interface{}) {
+			if name, ok := lifted[p]; ok {
+				if o.ref.Params == nil {
+					o.ref.Params = make(map[string]interface{})
+				}
+				o.ref.Params[name] = v
+			}
+		})
+	}
+	return replaceLeaves("", occurrences[0].value, lifted), params
+}
+
+// paramName names the param of the value at path p, e.g. "params.text", after
+// its key when short is set, after the whole path otherwise.
+func paramName(p string, short bool) string {
+	if short {
+		if i := strings.LastIndex(p, "."); i >= 0 {
+			p = p[i+1:]
+		}
+	}
+	return strings.Trim(paramNameRegexp.ReplaceAllString(p, "_"), "_")
+}
+
+func leaves(p string, v interface{}, fn func(string, interface{})) {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		for k, e := range vv {
+			leaves(joinPath(p, fmt.Sprint(k)), e, fn)
+		}
+	case []interface{}:
+		for i, e := range vv {
+			leaves(fmt.Sprintf("%s[%d]", p, i), e, fn)
+		}
+	default:
+		fn(p, v)
+	}
+}
+
+func replaceLeaves(p string, v interface{}, lifted map[string]string) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{})
+		for k, e := range vv {
+			res[k] = replaceLeaves(joinPath(p, fmt.Sprint(k)), e, lifted)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(vv))
+		for i, e := range vv {
+			res[i] = replaceLeaves(fmt.Sprintf("%s[%d]", p, i), e, lifted)
+		}
+		return res
+	}
+	if name, ok := lifted[p]; ok {
+		return "((" + name + "))"
+	}
+	return v
+}
+
+func joinPath(p, key string) string {
+	if p == "" {
+		return key
+	}
+	return p + "." + key
+}
+
+// stepName returns the name of Step s: the one of an equal Step already
+// defined, or name made unique.
+func (m *miner) stepName(name string, s Step) string {
+	for _, e := range m.opts.Steps.Steps {
+		if sameTemplate(e, s, e.Name) {
+			return e.Name
+		}
+	}
+	for _, e := range m.res.Steps.Steps {
+		if sameTemplate(e, s, e.Name) {
+			return e.Name
+		}
+	}
+	s.Name = m.uniqueName(name)
+	m.res.Steps.Steps = append(m.res.Steps.Steps, s)
+	return s.Name
+}
+
+// decoratorName is the counterpart of stepName for Decorators.
+func (m *miner) decoratorName(name string, d Decorator) string {
+	for _, e := range m.opts.Decorators.Decorators {
+		if sameTemplate(e, d, e.Name) {
+			return e.Name
+		}
+	}
+	for _, e := range m.res.Decorators.Decorators {
+		if sameTemplate(e, d, e.Name) {
+			return e.Name
+		}
+	}
+	d.Name = m.uniqueName(name)
+	m.res.Decorators.Decorators = append(m.res.Decorators.Decorators, d)
+	return d.Name
+}
+
+func (m *miner) uniqueName(name string) string {
+	name = strings.Trim(templateNameRegexp.ReplaceAllString(name, "-"), "-")
+	res := name
+	for i := 2; m.names[res]; i++ {
+		res = fmt.Sprintf("%s-%d", name, i)
+	}
+	m.names[res] = true
+	return res
+}
+
+// sameTemplate tells whether templates a and b only differ by name, b being
+// renamed name.
+func sameTemplate(a, b interface{}, name string) bool {
+	var va, vb interface{}
+	ba, err := yaml.Marshal(a)
+	if err != nil || yaml.Unmarshal(ba, &va) != nil {
+		return false
+	}
+	bb, err := yaml.Marshal(b)
+	if err != nil || yaml.Unmarshal(bb, &vb) != nil {
+		return false
+	}
+	if m, ok := vb.(map[interface{}]interface{}); ok {
+		m["name"] = name
+	}
+	return types.ValuesEqual(va, vb)
+}
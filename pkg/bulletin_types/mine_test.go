@@ -0,0 +1,302 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+)
+
+func TestMine(t *testing.T) {
+	tests := []struct {
+		name       string
+		jobs       string
+		steps      []string
+		decorators []string
+		refs       []string
+	}{
+		{
+			name: "steps found once are templates of their job",
+			jobs: `
+jobs:
+- name: a
+  plan:
+  - get: repo
+  - task: build
+    file: repo/build.yml
+  - aggregate:
+    - put: img1
+    - put: img2
+`,
+			steps: []string{"a-get-repo", "a-task-build", "a-plan-2"},
+			refs:  []string{"a: a-get-repo, a-task-build, a-plan-2"},
+		},
+		{
+			name: "differing strings are lifted into params, other values split templates",
+			jobs: `
+jobs:
+- name: unit
+  plan:
+  - get: repo
+  - task: test
+    file: repo/ci/test.yml
+    params: {MODE: unit}
+- name: lint
+  plan:
+  - get: repo
+    trigger: true
+  - task: test
+    file: repo/ci/test.yml
+    params: {MODE: lint}
+`,
+			steps: []string{"unit-get-repo", "task-test(MODE)", "lint-get-repo"},
+			refs: []string{
+				"unit: unit-get-repo, task-test{MODE: unit}",
+				"lint: lint-get-repo, task-test{MODE: lint}",
+			},
+		},
+		{
+			name: "repeated hooks become decorators",
+			jobs: `
+jobs:
+- name: unit
+  plan:
+  - task: unit
+    file: ci/unit.yml
+    on_failure: {put: slack, params: {text: unit failed}}
+- name: lint
+  plan:
+  - task: lint
+    file: ci/lint.yml
+  on_failure: {put: slack, params: {text: lint failed}}
+`,
+			steps:      []string{"unit-task-unit", "lint-task-lint"},
+			decorators: []string{"on-failure-put-slack(text)"},
+			refs: []string{
+				"unit: unit-task-unit[on-failure-put-slack{text: unit failed}]",
+				"lint: lint-task-lint; on-failure-put-slack{text: lint failed}",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := job.ParseJobs(tt.jobs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mined, err := Mine(jobs, MineOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var steps, decorators, refs []string
+			for _, s := range mined.Steps.Steps {
+				steps = append(steps, s.Name+params(s.Params))
+			}
+			for _, d := range mined.Decorators.Decorators {
+				decorators = append(decorators, d.Name+params(d.Params))
+			}
+			for _, j := range mined.Jobs.Jobs {
+				var plan []string
+				for _, s := range j.Plan {
+					ref := s.Name + args(s.Params)
+					var decs []string
+					for _, d := range s.Decorators {
+						decs = append(decs, d.Name+args(d.Params))
+					}
+					if len(decs) != 0 {
+						ref += "[" + strings.Join(decs, ", ") + "]"
+					}
+					plan = append(plan, ref)
+				}
+				ref := j.Name + ": " + strings.Join(plan, ", ")
+				for _, d := range j.Decorators {
+					ref += "; " + d.Name + args(d.Params)
+				}
+				refs = append(refs, ref)
+			}
+			assertStrings(t, "steps", steps, tt.steps)
+			assertStrings(t, "decorators", decorators, tt.decorators)
+			assertStrings(t, "refs", refs, tt.refs)
+		})
+	}
+}
+
+func TestMineExpandRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		jobs string
+	}{
+		{
+			name: "aggregate after other steps",
+			jobs: `
+jobs:
+- name: a
+  plan:
+  - get: repo
+  - task: build
+    file: repo/build.yml
+  - aggregate:
+    - put: img1
+    - put: img2
+`,
+		},
+		{
+			name: "lifted params, step and job hooks",
+			jobs: `
+jobs:
+- name: unit
+  serial: true
+  plan:
+  - aggregate:
+    - get: repo
+      trigger: true
+    - get: tools
+  - task: test
+    file: repo/ci/test.yml
+    params: {MODE: unit}
+    on_failure: {put: slack, params: {text: unit failed}}
+  - try: {put: report, params: {file: out/unit.xml}}
+- name: lint
+  plan:
+  - get: repo
+    passed: [unit]
+  - task: test
+    file: repo/ci/test.yml
+    params: {MODE: lint}
+  on_failure: {put: slack, params: {text: lint failed}}
+  ensure: {put: lock, params: {release: lock}}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := job.ParseJobs(tt.jobs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mined, err := Mine(jobs, MineOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := mined.Jobs.Expand(mined.Decorators, mined.Steps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSameYAML(t, got, jobs)
+		})
+	}
+}
+
+func TestMinedRedact(t *testing.T) {
+	jobs, err := job.ParseJobs(`
+jobs:
+- name: unit
+  plan:
+  - task: unit
+    file: repo/unit.yml
+    params: {DB_PASSWORD: hunter2, MODE: unit}
+  on_failure:
+    put: alert
+    params: {text: ko, token: abc}
+- name: lint
+  plan:
+  - task: unit
+    file: repo/unit.yml
+    params: {DB_PASSWORD: other, MODE: lint}
+  - put: img
+    params: {password: ((registry_password))}
+  on_failure:
+    put: alert
+    params: {text: ko, token: abc}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mined, err := Mine(jobs, MineOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted, secrets, err := mined.Redact()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, s := range secrets {
+		paths = append(paths, s.Path+" "+s.Var)
+	}
+	want := []string{
+		"decorators[on-failure-put-alert].on_failure.params.token decorators_on-failure-put-alert_on_failure_params_token",
+		"jobs[unit].plan[0].params.DB_PASSWORD jobs_unit_0_DB_PASSWORD",
+		"jobs[lint].plan[0].params.DB_PASSWORD jobs_lint_0_DB_PASSWORD",
+	}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got secrets:\n%s\nwant:\n%s", strings.Join(paths, "\n"), strings.Join(want, "\n"))
+	}
+	out := redacted.Steps.String() + redacted.Decorators.String() + redacted.Jobs.String()
+	for _, s := range []string{"hunter2", "other", "abc"} {
+		if strings.Contains(out, s) {
+			t.Errorf("%s left in plain text:\n%s", s, out)
+		}
+	}
+	if !strings.Contains(mined.Jobs.String(), "hunter2") {
+		t.Error("Redact modified the mined jobs")
+	}
+}
+
+// params formats the params of a template definition.
+func params(ps []string) string {
+	if len(ps) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(ps, ", ") + ")"
+}
+
+// args formats the params of a template reference.
+func args(ps map[string]interface{}) string {
+	if len(ps) == 0 {
+		return ""
+	}
+	b, _ := yaml.Marshal(ps)
+	return "{" + strings.Replace(strings.TrimSpace(string(b)), "\n", ", ", -1) + "}"
+}
+
+func assertStrings(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s %q, want %q", what, got, want)
+	}
+}
+
+// assertSameYAML compares got and want through their yaml representation.
+func assertSameYAML(t *testing.T, got, want interface{}) {
+	t.Helper()
+	var g, w interface{}
+	gb, err := yaml.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wb, err := yaml.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(gb, &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(wb, &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got:\n%s\nwant:\n%s", gb, wb)
+	}
+}
@@ -0,0 +1,139 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+)
+
+// Selector designates the jobs, or the steps of jobs, a decorator applies to.
+// It is written JOB or JOB/STEP, STEP being optionally prefixed by a step
+// type name as in TYPE:NAME, e.g.:
+//
+//	deploy-*           jobs named deploy-something
+//	deploy-*/run-*     steps named run-something of these jobs
+//	*/put:slack-*      puts to resources named slack-something of every job
+//	*/task:            every task of every job
+//
+// Patterns are globs, * matching any string, or regular expressions when
+// prefixed by ~, e.g. ~^(unit|lint)$/task:. Steps are named after their
+// template reference and after the resource, task, pipeline or var they
+// designate.
+type Selector struct {
+	Job  string
+	Step string
+	// Type restricts the steps selected to one type, e.g. "put"
+	Type string
+	job  *regexp.Regexp
+	step *regexp.Regexp
+	t    job.Type
+}
+
+func GetSelectorFromString(s string) Selector {
+	res, err := ParseSelector(s)
+	berror.CheckError(err)
+	return res
+}
+
+// ParseSelector is the error returning counterpart of GetSelectorFromString.
+func ParseSelector(s string) (Selector, error) {
+	res := Selector{}
+	parts := strings.SplitN(s, "/", 2)
+	res.Job = parts[0]
+	if res.Job == "" {
+		return res, fmt.Errorf("invalid selector %q: no job", s)
+	}
+	var err error
+	res.job, err = compilePattern(res.Job)
+	if err != nil {
+		return res, fmt.Errorf("invalid selector %q: %v", s, err)
+	}
+	if len(parts) == 1 {
+		return res, nil
+	}
+	res.Step = parts[1]
+	// the text before : is a step type only when it names one, patterns such
+	// as ~(?:unit|lint) hold colons too
+	if i := strings.Index(res.Step, ":"); i >= 0 {
+		if t, err := job.TypeFromString(res.Step[:i]); err == nil {
+			res.Type, res.Step, res.t = res.Step[:i], res.Step[i+1:], t
+			if res.Step == "" {
+				res.Step = "*"
+			}
+		}
+	}
+	if res.Step == "" {
+		return res, fmt.Errorf("invalid selector %q: no step", s)
+	}
+	res.step, err = compilePattern(res.Step)
+	if err != nil {
+		return res, fmt.Errorf("invalid selector %q: %v", s, err)
+	}
+	return res, nil
+}
+
+func (s Selector) String() string {
+	res := s.Job
+	if s.Type != "" {
+		return res + "/" + s.Type + ":" + s.Step
+	}
+	if s.Step != "" {
+		res += "/" + s.Step
+	}
+	return res
+}
+
+// SelectsJobs tells whether s designates jobs rather than steps.
+func (s Selector) SelectsJobs() bool {
+	return s.Step == ""
+}
+
+func (s Selector) MatchJob(name string) bool {
+	return s.job != nil && s.job.MatchString(name)
+}
+
+// MatchStep tells whether s selects a step of type t, known by names, among
+// the steps of the jobs it matches.
+func (s Selector) MatchStep(t job.Type, names ...string) bool {
+	if s.step == nil {
+		return false
+	}
+	if s.Type != "" && s.t != t {
+		return false
+	}
+	for _, n := range names {
+		if n != "" && s.step.MatchString(n) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePattern compiles a glob, or a regular expression prefixed by ~.
+func compilePattern(p string) (*regexp.Regexp, error) {
+	if strings.HasPrefix(p, "~") {
+		return regexp.Compile(p[1:])
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range p {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
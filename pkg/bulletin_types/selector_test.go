@@ -0,0 +1,84 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"testing"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector string
+		job      string
+		step     string
+		typ      string
+		err      bool
+	}{
+		{selector: "deploy-*", job: "deploy-*"},
+		{selector: "deploy-*/run-*", job: "deploy-*", step: "run-*"},
+		{selector: "*/put:slack-*", job: "*", step: "slack-*", typ: "put"},
+		{selector: "*/task:", job: "*", step: "*", typ: "task"},
+		{selector: "~^(unit|lint)$/task:", job: "~^(unit|lint)$", step: "*", typ: "task"},
+		{selector: "*/~(?:unit|lint)", job: "*", step: "~(?:unit|lint)"},
+		{selector: "*/task:~(?:unit|lint)", job: "*", step: "~(?:unit|lint)", typ: "task"},
+		{selector: "*/image:tag", job: "*", step: "image:tag"},
+		{selector: "", err: true},
+		{selector: "/task:unit", err: true},
+		{selector: "build/", err: true},
+		{selector: "~(/unit", err: true},
+		{selector: "build/~(?:unit", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			got, err := ParseSelector(tt.selector)
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Job != tt.job || got.Step != tt.step || got.Type != tt.typ {
+				t.Errorf("got job %q, step %q, type %q, want %q, %q, %q", got.Job, got.Step, got.Type, tt.job, tt.step, tt.typ)
+			}
+			again, err := ParseSelector(got.String())
+			if err != nil || again.String() != got.String() {
+				t.Errorf("%s does not parse back: %+v, %v", got, again, err)
+			}
+		})
+	}
+}
+
+func TestSelectorMatch(t *testing.T) {
+	tests := []struct {
+		selector string
+		job      string
+		t        job.Type
+		names    []string
+		want     bool
+	}{
+		{"deploy-*/run-*", "deploy-prod", job.TaskStepType, []string{"run-smoke"}, true},
+		{"deploy-*/run-*", "build", job.TaskStepType, []string{"run-smoke"}, false},
+		{"*/put:slack-*", "build", job.PutStepType, []string{"slack-alert"}, true},
+		{"*/put:slack-*", "build", job.GetStepType, []string{"slack-alert"}, false},
+		{"*/~(?:unit|lint)", "build", job.TaskStepType, []string{"", "lint"}, true},
+		{"*/task:~^(?:unit|lint)$", "build", job.TaskStepType, []string{"e2e"}, false},
+	}
+	for _, tt := range tests {
+		s, err := ParseSelector(tt.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := s.MatchJob(tt.job) && s.MatchStep(tt.t, tt.names...)
+		if got != tt.want {
+			t.Errorf("%s matching %s %s %q = %t, want %t", tt.selector, tt.job, tt.t, tt.names, got, tt.want)
+		}
+	}
+}
@@ -15,7 +15,9 @@ import (
 
 	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/registry"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/yamlnode"
 )
 
 const (
@@ -24,8 +26,9 @@ const (
 )
 
 type Steps struct {
-	Steps []Step          `yaml:"steps"`
-	cache map[string]Step `yaml:",omitempty"`
+	Steps    []Step          `yaml:"steps"`
+	cache    map[string]Step `yaml:",omitempty"`
+	registry *registry.Registry
 }
 
 func (s *Steps) String() string {
@@ -34,6 +37,9 @@ func (s *Steps) String() string {
 	return string(b[:])
 }
 
+// Populate instantiates the Step referenced by r. The Steps of the flat layout
+// are looked up first, whatever their name; namespace/name@version designates
+// a Step of the registry otherwise.
 func (s *Steps) Populate(r template.TemplateRef) (Step, error) {
 	if s.cache == nil {
 		s.cache = make(map[string]Step)
@@ -41,9 +47,23 @@ func (s *Steps) Populate(r template.TemplateRef) (Step, error) {
 			s.cache[st.Name] = st
 		}
 	}
-	v, ok := s.cache[r.Name]
-	if !ok {
-		return Step{}, errors.New("no referenced Step definition")
+	if v, ok := s.cache[r.Name]; ok {
+		return v.Populate(r)
+	}
+	ref, err := registry.ParseRef(r.Name)
+	if err != nil || ref.IsLocal() {
+		return Step{}, fmt.Errorf("no referenced Step definition %s", r.Name)
+	}
+	if s.registry == nil {
+		return Step{}, fmt.Errorf("no registry to resolve Step %s", ref)
+	}
+	_, data, err := s.registry.Load(registry.StepsKind, ref)
+	if err != nil {
+		return Step{}, err
+	}
+	v, err := getStepFromString(data)
+	if err != nil {
+		return Step{}, err
 	}
 	return v.Populate(r)
 }
@@ -72,30 +92,40 @@ func (s *Step) GetStep() interface{} {
 	return s.Step
 }
 
+// Equal tells whether i is a Step of the same name, params and body.
 func (s Step) Equal(i interface{}) bool {
 	switch v := i.(type) {
 	case Step:
-		return false
+		return sameDefinition(s, v)
 	case *Step:
-		return false
-	default:
-		fmt.Printf("unsupported type %s\n", v)
+		return v != nil && sameDefinition(s, *v)
 	}
-	return true
+	return false
 }
 
 func GetStepsFromString(data string) Steps {
-	r := Steps{}
-	err := yaml.Unmarshal([]byte(data), &r)
+	r, err := ParseSteps(data)
 	berror.CheckError(err)
 	return r
 }
 
-func getStepFromString(data string) Step {
+// ParseSteps is the error returning counterpart of GetStepsFromString.
+func ParseSteps(data string) (Steps, error) {
+	r := Steps{}
+	err := yaml.Unmarshal([]byte(data), &r)
+	if err != nil {
+		return r, berror.FromYAML(err)
+	}
+	return r, nil
+}
+
+func getStepFromString(data string) (Step, error) {
 	r := Step{}
 	err := yaml.Unmarshal([]byte(data), &r)
-	berror.CheckError(err)
-	return r
+	if err != nil {
+		return r, berror.FromYAML(err)
+	}
+	return r, nil
 }
 
 func GetStep(s string) (Step, error) {
@@ -105,31 +135,51 @@ func GetStep(s string) (Step, error) {
 	}
 	switch t {
 	case StepType:
-		return getStepFromString(s), nil
+		return getStepFromString(s)
 	default:
 		return Step{}, errors.New(fmt.Sprintf("not a Step"))
 	}
 }
 
+// SaveStepsLocally updates the steps file of target with s, keeping the
+// comments, order and anchors of the steps left unchanged.
+func SaveStepsLocally(target string, s Steps) error {
+	return yamlnode.UpdateFile(filepath.Join(target, stepsDir, stepsFile), s)
+}
+
 func GetLocalSteps(target string) Steps {
-	targetDir := filepath.Join(target, stepsDir)
-	ioutils.CreateDirIfNotExist(targetDir)
-	targetFile := filepath.Join(targetDir, stepsFile)
-	ioutils.CreateFileIfNotExist(targetFile)
-	content := ioutils.ReadFile(targetFile)
-	steps := GetStepsFromString(content)
+	res, err := LoadLocalSteps(target)
+	berror.CheckError(err)
+	return res
+}
+
+// LoadLocalSteps is the error returning counterpart of GetLocalSteps.
+func LoadLocalSteps(target string) (Steps, error) {
+	res := Steps{registry: registry.New(target)}
+	targetFile := filepath.Join(target, stepsDir, stepsFile)
+	content, err := ioutils.LoadOrCreateFile(targetFile)
+	if err != nil {
+		return res, err
+	}
+	steps, err := ParseSteps(content)
+	if err != nil {
+		return res, berror.WithFile(err, targetFile)
+	}
 	resSet := types.NewSet()
 	for _, d := range steps.Steps {
 		resSet.Add(d)
 	}
-	res := Steps{}
+	names := make(map[string]bool)
 	for _, d := range resSet.Get() {
-		switch v := d.(type) {
-		case Step:
-			res.Steps = append(res.Steps, v)
-		default:
-			fmt.Printf("unsupported type %s\n", v)
+		v, ok := d.(Step)
+		if !ok {
+			return res, berror.WithFile(fmt.Errorf("unsupported type %T", d), targetFile)
 		}
+		if names[v.Name] {
+			return res, berror.WithFile(berror.Errorf(fmt.Sprintf("steps[%s]", v.Name), "Step defined twice with different bodies"), targetFile)
+		}
+		names[v.Name] = true
+		res.Steps = append(res.Steps, v)
 	}
-	return res
+	return res, nil
 }
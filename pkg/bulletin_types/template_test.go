@@ -0,0 +1,198 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package bulletin_types
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	template "github.com/maplain/yamltemplate"
+)
+
+func TestPopulateLookup(t *testing.T) {
+	names := []string{"build", "notify@slack", "team/notify", "image:tag", `win\path`}
+	steps := Steps{}
+	decorators := Decorators{}
+	jobs := JobTemplates{}
+	for _, n := range names {
+		steps.Steps = append(steps.Steps, Step{TemplateDef: template.TemplateDef{Name: n}, Step: map[interface{}]interface{}{"task": n}})
+		decorators.Decorators = append(decorators.Decorators, Decorator{TemplateDef: template.TemplateDef{Name: n}})
+		jobs.Jobs = append(jobs.Jobs, JobTemplate{TemplateDef: template.TemplateDef{Name: n}, Job: map[interface{}]interface{}{"name": n}})
+	}
+	tests := []struct {
+		name string
+		err  string
+	}{
+		{name: "build"},
+		{name: "notify@slack"},
+		{name: "team/notify"},
+		{name: "image:tag"},
+		{name: `win\path`},
+		{name: "deploy", err: "no referenced"},
+		{name: "deploy:prod", err: "no referenced"},
+		{name: "team/deploy@1.0.0", err: "no registry"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := template.TemplateRef{Name: tt.name}
+			st, err := steps.Populate(r)
+			check(t, "Step", err, tt.err)
+			if err == nil && st.Name != tt.name {
+				t.Errorf("got Step %s", st.Name)
+			}
+			d, err := decorators.Populate(r)
+			check(t, "Decorator", err, tt.err)
+			if err == nil && d.Name != tt.name {
+				t.Errorf("got Decorator %s", d.Name)
+			}
+			j, err := jobs.Populate(r)
+			check(t, "JobTemplate", err, tt.err)
+			if err == nil && j.Name != tt.name {
+				t.Errorf("got JobTemplate %s", j.Name)
+			}
+		})
+	}
+}
+
+func TestLoadLocalTemplates(t *testing.T) {
+	// every layout file is written from the same definitions, TEMPLATES and
+	// BODY standing for its list and body keys
+	layouts := []struct {
+		what string
+		file string
+		list string
+		body string
+		load func(string) ([]string, error)
+	}{
+		{
+			what: "Step",
+			file: filepath.Join(stepsDir, stepsFile),
+			list: "steps",
+			body: "step",
+			load: func(dir string) (res []string, err error) {
+				steps, err := LoadLocalSteps(dir)
+				for _, s := range steps.Steps {
+					res = append(res, s.Name)
+				}
+				return res, err
+			},
+		},
+		{
+			what: "Decorator",
+			file: filepath.Join(decoratorsDir, decoratorsFile),
+			list: "decorators",
+			body: "on_success",
+			load: func(dir string) (res []string, err error) {
+				decs, err := LoadLocalDecorators(dir)
+				for _, d := range decs.Decorators {
+					res = append(res, d.Name)
+				}
+				return res, err
+			},
+		},
+		{
+			what: "JobTemplate",
+			file: filepath.Join(jobTemplatesDir, jobTemplatesFile),
+			list: "jobs",
+			body: "job",
+			load: func(dir string) (res []string, err error) {
+				jobs, err := LoadLocalJobTemplates(dir)
+				for _, j := range jobs.Jobs {
+					res = append(res, j.Name)
+				}
+				return res, err
+			},
+		},
+	}
+	tests := []struct {
+		name string
+		data string
+		want []string
+		// conflict is true when unit is defined twice
+		conflict bool
+	}{
+		{
+			name: "identical definitions are deduplicated",
+			data: `
+TEMPLATES:
+- name: unit
+  BODY: {task: unit, file: ci/unit.yml}
+- name: lint
+  BODY: {task: lint, file: ci/lint.yml}
+- name: unit
+  BODY: {file: ci/unit.yml, task: unit}
+`,
+			want: []string{"unit", "lint"},
+		},
+		{
+			name: "params tell definitions apart",
+			data: `
+TEMPLATES:
+- name: unit
+  BODY: {task: unit}
+- name: unit
+  params: [MODE]
+  BODY: {task: unit}
+`,
+			conflict: true,
+		},
+		{
+			name: "differing bodies under one name",
+			data: `
+TEMPLATES:
+- name: unit
+  BODY: {task: unit, file: ci/unit.yml}
+- name: unit
+  BODY: {task: unit, file: ci/other.yml}
+`,
+			conflict: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "templates")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for _, l := range layouts {
+				file := filepath.Join(dir, l.file)
+				if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+					t.Fatal(err)
+				}
+				data := strings.NewReplacer("TEMPLATES", l.list, "BODY", l.body).Replace(tt.data)
+				if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+				got, err := l.load(dir)
+				want := ""
+				if tt.conflict {
+					want = fmt.Sprintf("%s[unit]: %s defined twice with different bodies", l.list, l.what)
+				}
+				check(t, l.what, err, want)
+				if want == "" {
+					assertStrings(t, l.what+"s", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// check fails t when err does not contain want, or is not nil when want is
+// empty.
+func check(t *testing.T, what string, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("%s: %v", what, err)
+	case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+		t.Errorf("%s: got error %v, want %q", what, err, want)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"fmt"
 
 	yaml "gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
 )
 
 type Type int
@@ -45,3 +47,21 @@ func GetType(s string) (Type, error) {
 		return UnrecognizedType, errors.New(fmt.Sprintf("Unrecognized type:%s", vs))
 	}
 }
+
+// sameDefinition tells whether the templates a and b are written the same,
+// comparing their yaml representation.
+func sameDefinition(a, b interface{}) bool {
+	var av, bv interface{}
+	ab, err := yaml.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := yaml.Marshal(b)
+	if err != nil {
+		return false
+	}
+	if yaml.Unmarshal(ab, &av) != nil || yaml.Unmarshal(bb, &bv) != nil {
+		return false
+	}
+	return types.ValuesEqual(av, bv)
+}
@@ -0,0 +1,416 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a single difference between two pipelines. Path locates it, e.g.
+// "resources[src].source.branch" or "jobs[build].plan[task:unit].file". Plan
+// steps are designated by type and name, composite steps by type and rank.
+type Change struct {
+	Kind   ChangeKind  `json:"kind"`
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type Diff struct {
+	Changes []Change `json:"changes"`
+}
+
+func (d *Diff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+func (d *Diff) add(kind ChangeKind, path string, before, after interface{}) {
+	d.Changes = append(d.Changes, Change{Kind: kind, Path: path, Before: before, After: after})
+}
+
+// Pipelines computes the semantic differences between pipelines a and b:
+// resources, resource types, groups and jobs are matched by name, and plan
+// steps by type and name, so that key order, formatting and omitted default
+// values do not show up.
+func Pipelines(a, b pipeline.Pipeline) (Diff, error) {
+	d := Diff{}
+
+	bResources := b.Resources.Map()
+	for _, ra := range a.Resources.Resources {
+		path := fmt.Sprintf("resources[%s]", ra.Name)
+		rb, ok := bResources[ra.Name]
+		if !ok {
+			d.add(Removed, path, ra, nil)
+			continue
+		}
+		// resources equal by identity may still differ by mergeable fields
+		err := d.values(path, ra, rb)
+		if err != nil {
+			return d, berror.WithPath(err, path)
+		}
+	}
+	aResources := a.Resources.Map()
+	for _, rb := range b.Resources.Resources {
+		if _, ok := aResources[rb.Name]; !ok {
+			d.add(Added, fmt.Sprintf("resources[%s]", rb.Name), nil, rb)
+		}
+	}
+
+	bTypes := b.ResourceTypes.Map()
+	for _, ta := range a.ResourceTypes.ResourceTypes {
+		path := fmt.Sprintf("resource_types[%s]", ta.Name)
+		tb, ok := bTypes[ta.Name]
+		if !ok {
+			d.add(Removed, path, ta, nil)
+			continue
+		}
+		equal, err := ta.Compare(tb)
+		if err != nil {
+			return d, berror.WithPath(err, path)
+		}
+		if !equal {
+			err = d.values(path, ta, tb)
+			if err != nil {
+				return d, berror.WithPath(err, path)
+			}
+		}
+	}
+	aTypes := a.ResourceTypes.Map()
+	for _, tb := range b.ResourceTypes.ResourceTypes {
+		if _, ok := aTypes[tb.Name]; !ok {
+			d.add(Added, fmt.Sprintf("resource_types[%s]", tb.Name), nil, tb)
+		}
+	}
+
+	bGroups := make(map[string]interface{})
+	for _, g := range b.Groups.Groups {
+		bGroups[g.Name] = g
+	}
+	aGroups := make(map[string]bool)
+	for _, ga := range a.Groups.Groups {
+		aGroups[ga.Name] = true
+		path := fmt.Sprintf("groups[%s]", ga.Name)
+		gb, ok := bGroups[ga.Name]
+		if !ok {
+			d.add(Removed, path, ga, nil)
+			continue
+		}
+		err := d.values(path, ga, gb)
+		if err != nil {
+			return d, berror.WithPath(err, path)
+		}
+	}
+	for _, gb := range b.Groups.Groups {
+		if !aGroups[gb.Name] {
+			d.add(Added, fmt.Sprintf("groups[%s]", gb.Name), nil, gb)
+		}
+	}
+
+	bJobs := make(map[string]job.Job)
+	for _, j := range b.Jobs.Jobs {
+		bJobs[j.Name] = j
+	}
+	aJobs := make(map[string]bool)
+	for _, ja := range a.Jobs.Jobs {
+		aJobs[ja.Name] = true
+		path := fmt.Sprintf("jobs[%s]", ja.Name)
+		jb, ok := bJobs[ja.Name]
+		if !ok {
+			d.add(Removed, path, ja, nil)
+			continue
+		}
+		err := d.jobs(path, ja, jb)
+		if err != nil {
+			return d, berror.WithPath(err, path)
+		}
+	}
+	for _, jb := range b.Jobs.Jobs {
+		if !aJobs[jb.Name] {
+			d.add(Added, fmt.Sprintf("jobs[%s]", jb.Name), nil, jb)
+		}
+	}
+	return d, nil
+}
+
+func (d *Diff) jobs(path string, a, b job.Job) error {
+	am, err := toMap(a.JobBase)
+	if err != nil {
+		return err
+	}
+	bm, err := toMap(b.JobBase)
+	if err != nil {
+		return err
+	}
+	d.maps(path, am, bm)
+	err = d.hooks(path, a.StepHooks, b.StepHooks)
+	if err != nil {
+		return err
+	}
+	return d.plan(path+".plan", a.Plan, b.Plan)
+}
+
+func (d *Diff) hooks(path string, a, b job.StepHooks) error {
+	hooks := []struct {
+		key  string
+		a, b interface{}
+	}{
+		{"on_success", a.OnSuccess, b.OnSuccess},
+		{"on_failure", a.OnFailure, b.OnFailure},
+		{"on_error", a.OnError, b.OnError},
+		{"on_abort", a.OnAbort, b.OnAbort},
+		{"ensure", a.Ensure, b.Ensure},
+	}
+	for _, h := range hooks {
+		err := d.hook(path+"."+h.key, h.a, h.b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Diff) hook(path string, a, b interface{}) error {
+	switch {
+	case a == nil && b == nil:
+		return nil
+	case a == nil:
+		d.add(Added, path, nil, b)
+		return nil
+	case b == nil:
+		d.add(Removed, path, a, nil)
+		return nil
+	}
+	ka, err := stepKeys([]interface{}{a})
+	if err != nil {
+		return err
+	}
+	kb, err := stepKeys([]interface{}{b})
+	if err != nil {
+		return err
+	}
+	if ka[0] != kb[0] {
+		d.add(Changed, path, a, b)
+		return nil
+	}
+	return d.step(path, a, b)
+}
+
+// plan matches the steps of a and b by key, reporting removed, added and
+// changed steps, then steps that kept their key but moved.
+func (d *Diff) plan(path string, a, b []interface{}) error {
+	ka, err := stepKeys(a)
+	if err != nil {
+		return err
+	}
+	kb, err := stepKeys(b)
+	if err != nil {
+		return err
+	}
+	bsteps := make(map[string]interface{})
+	for i, k := range kb {
+		bsteps[k] = b[i]
+	}
+	asteps := make(map[string]bool)
+	var aorder, border []string
+	for i, k := range ka {
+		asteps[k] = true
+		spath := fmt.Sprintf("%s[%s]", path, k)
+		bs, ok := bsteps[k]
+		if !ok {
+			d.add(Removed, spath, a[i], nil)
+			continue
+		}
+		aorder = append(aorder, k)
+		err = d.step(spath, a[i], bs)
+		if err != nil {
+			return err
+		}
+	}
+	for i, k := range kb {
+		if !asteps[k] {
+			d.add(Added, fmt.Sprintf("%s[%s]", path, k), nil, b[i])
+			continue
+		}
+		border = append(border, k)
+	}
+	if !types.StringSliceEqual(aorder, border) {
+		d.add(Changed, path, aorder, border)
+	}
+	return nil
+}
+
+func (d *Diff) step(path string, a, b interface{}) error {
+	am, err := toMap(a)
+	if err != nil {
+		return err
+	}
+	bm, err := toMap(b)
+	if err != nil {
+		return err
+	}
+	ak, achildren, err := job.Children(a)
+	if err != nil {
+		return err
+	}
+	_, bchildren, err := job.Children(b)
+	if err != nil {
+		return err
+	}
+	if ak != "" {
+		err = d.plan(path+"."+ak, achildren, bchildren)
+		if err != nil {
+			return err
+		}
+		// keep the options of the map form of in_parallel
+		am[ak] = withoutSteps(ak, am[ak])
+		bm[ak] = withoutSteps(ak, bm[ak])
+	}
+	ah, bh := job.StepHooks{}, job.StepHooks{}
+	err = convert(a, &ah)
+	if err != nil {
+		return err
+	}
+	err = convert(b, &bh)
+	if err != nil {
+		return err
+	}
+	err = d.hooks(path, ah, bh)
+	if err != nil {
+		return err
+	}
+	for _, h := range []string{"on_success", "on_failure", "on_error", "on_abort", "ensure"} {
+		delete(am, h)
+		delete(bm, h)
+	}
+	d.maps(path, am, bm)
+	return nil
+}
+
+func (d *Diff) values(path string, a, b interface{}) error {
+	am, err := toMap(a)
+	if err != nil {
+		return err
+	}
+	bm, err := toMap(b)
+	if err != nil {
+		return err
+	}
+	d.maps(path, am, bm)
+	return nil
+}
+
+// maps reports the keys of a and b having different values, recursing into
+// nested maps. A missing key is equal to a key set to its zero value.
+func (d *Diff) maps(path string, a, b map[interface{}]interface{}) {
+	var keys []interface{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+	for _, k := range keys {
+		kpath := fmt.Sprintf("%s.%v", path, k)
+		av, bv := a[k], b[k]
+		if types.ValuesEqual(av, bv) {
+			continue
+		}
+		am, aok := av.(map[interface{}]interface{})
+		bm, bok := bv.(map[interface{}]interface{})
+		switch {
+		case aok && bok:
+			d.maps(kpath, am, bm)
+		case types.IsZeroValue(av):
+			d.add(Added, kpath, nil, bv)
+		case types.IsZeroValue(bv):
+			d.add(Removed, kpath, av, nil)
+		default:
+			d.add(Changed, kpath, av, bv)
+		}
+	}
+}
+
+// stepKeys designates steps by type and name, e.g. "get:src", and composite
+// or unnamed steps by type and rank among the steps of that type, e.g.
+// "in_parallel#0".
+func stepKeys(steps []interface{}) ([]string, error) {
+	var res []string
+	seen := make(map[string]int)
+	for i, s := range steps {
+		t, err := job.TypeOf(s)
+		if err != nil {
+			return res, berror.WithPath(err, fmt.Sprintf("[%d]", i))
+		}
+		key := t.String()
+		if !t.IsComposite() && t != job.UnrecognizedType {
+			name, err := job.GetStepName(s)
+			if err != nil {
+				return res, berror.WithPath(err, fmt.Sprintf("[%d]", i))
+			}
+			key += ":" + name
+		}
+		n := seen[key]
+		seen[key]++
+		if t.IsComposite() || t == job.UnrecognizedType || n != 0 {
+			key = fmt.Sprintf("%s#%d", key, n)
+		}
+		res = append(res, key)
+	}
+	return res, nil
+}
+
+func withoutSteps(key string, v interface{}) interface{} {
+	// the map form of try is the step tried, compared as a child
+	if key != job.InParallelStepType.String() {
+		return nil
+	}
+	// the list form of in_parallel has no option, so that the options of
+	// its map form are reported one by one
+	res := make(map[interface{}]interface{})
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return res
+	}
+	for k, vv := range m {
+		if k != "steps" {
+			res[k] = vv
+		}
+	}
+	return res
+}
+
+func convert(i interface{}, out interface{}) error {
+	d, err := yaml.Marshal(i)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(d, out)
+}
+
+func toMap(i interface{}) (map[interface{}]interface{}, error) {
+	res := make(map[interface{}]interface{})
+	err := convert(i, &res)
+	return res, err
+}
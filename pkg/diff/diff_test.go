@@ -0,0 +1,192 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+func TestPipelines(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want string
+	}{
+		{
+			name: "formatting, key order and defaults",
+			a: `
+resources:
+- name: repo
+  type: git
+  source: {uri: "git@example.com:ci.git", branch: master}
+jobs:
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+  - task: unit
+    file: repo/unit.yml
+`,
+			b: `
+jobs:
+- plan:
+  - trigger: true
+    get: repo
+    passed: []
+  - file: repo/unit.yml
+    task: unit
+    privileged: false
+  name: unit
+resources:
+- source:
+    branch: master
+    uri: "git@example.com:ci.git"
+  type: git
+  name: repo
+`,
+		},
+		{
+			name: "added, removed and changed resources",
+			a: `
+resources:
+- name: repo
+  type: git
+  source: {uri: "git@example.com:ci.git", branch: master}
+- name: img
+  type: registry-image
+`,
+			b: `
+resources:
+- name: repo
+  type: git
+  source: {uri: "git@example.com:ci.git", branch: develop, paths: [ci]}
+- name: every-day
+  type: time
+`,
+			want: `~ resources[repo].source.branch: "master" -> "develop"
++ resources[repo].source.paths
+- resources[img]
++ resources[every-day]
+`,
+		},
+		{
+			name: "added, removed and changed jobs",
+			a: `
+jobs:
+- name: unit
+  serial: true
+  plan:
+  - get: repo
+- name: lint
+  plan:
+  - get: repo
+`,
+			b: `
+jobs:
+- name: unit
+  plan:
+  - get: repo
+  on_failure: {put: slack}
+- name: e2e
+  plan:
+  - get: repo
+`,
+			want: `- jobs[unit].serial
++ jobs[unit].on_failure
+- jobs[lint]
++ jobs[e2e]
+`,
+		},
+		{
+			name: "plan steps matched by type and name",
+			a: `
+jobs:
+- name: unit
+  plan:
+  - get: repo
+  - task: unit
+    file: repo/unit.yml
+  - task: lint
+    file: repo/lint.yml
+  - put: img
+`,
+			b: `
+jobs:
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+  - task: lint
+    file: repo/lint.yml
+  - task: unit
+    file: repo/ci/unit.yml
+  - put: report
+`,
+			want: `+ jobs[unit].plan[get:repo].trigger
+~ jobs[unit].plan[task:unit].file: "repo/unit.yml" -> "repo/ci/unit.yml"
+- jobs[unit].plan[put:img]
++ jobs[unit].plan[put:report]
+~ jobs[unit].plan: ["get:repo","task:unit","task:lint"] -> ["get:repo","task:lint","task:unit"]
+`,
+		},
+		{
+			name: "nested steps",
+			a: `
+jobs:
+- name: unit
+  plan:
+  - in_parallel:
+    - get: repo
+    - get: tools
+  - try: {put: report}
+`,
+			b: `
+jobs:
+- name: unit
+  plan:
+  - in_parallel:
+      steps:
+      - get: repo
+      fail_fast: true
+  - try: {put: report, params: {file: out.xml}}
+`,
+			want: `- jobs[unit].plan[in_parallel#0].in_parallel[get:tools]
++ jobs[unit].plan[in_parallel#0].in_parallel.fail_fast
++ jobs[unit].plan[try#0].try[put:report].params
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := pipeline.ParsePipeline(tt.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := pipeline.ParsePipeline(tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := Pipelines(a, b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.Text()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			// --exit-code fails on any change
+			if d.Empty() != (tt.want == "") {
+				t.Errorf("got Empty() = %t with changes:\n%s", d.Empty(), got)
+			}
+		})
+	}
+}
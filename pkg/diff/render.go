@@ -0,0 +1,105 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+
+	UnsupportedFormatError types.InternalError = "unsupported diff format"
+)
+
+func (d *Diff) Render(format string) (string, error) {
+	switch format {
+	case TextFormat:
+		return d.Text()
+	case JSONFormat:
+		b, err := d.JSON()
+		if err != nil {
+			return "", err
+		}
+		return string(b[:]), nil
+	default:
+		return "", UnsupportedFormatError
+	}
+}
+
+// Text renders one change per line, prefixed with "+" when added, "-" when
+// removed and "~" when changed. Changed values are printed inline.
+func (d *Diff) Text() (string, error) {
+	var b bytes.Buffer
+	for _, c := range d.Changes {
+		switch c.Kind {
+		case Added:
+			fmt.Fprintf(&b, "+ %s\n", c.Path)
+		case Removed:
+			fmt.Fprintf(&b, "- %s\n", c.Path)
+		case Changed:
+			before, err := json.Marshal(jsonValue(c.Before))
+			if err != nil {
+				return "", err
+			}
+			after, err := json.Marshal(jsonValue(c.After))
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "~ %s: %s -> %s\n", c.Path, before, after)
+		}
+	}
+	return b.String(), nil
+}
+
+func (d *Diff) JSON() ([]byte, error) {
+	res := Diff{Changes: []Change{}}
+	for _, c := range d.Changes {
+		c.Before = jsonValue(c.Before)
+		c.After = jsonValue(c.After)
+		res.Changes = append(res.Changes, c)
+	}
+	return json.MarshalIndent(res, "", "  ")
+}
+
+// jsonValue converts v to a value encoding/json can marshal: typed models
+// and maps decoded from yaml, whose keys are not strings, become string keyed
+// maps.
+func jsonValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	var i interface{}
+	err := convert(v, &i)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return stringKeys(i)
+}
+
+func stringKeys(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{})
+		for k, e := range vv {
+			res[fmt.Sprint(k)] = stringKeys(e)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(vv))
+		for i, e := range vv {
+			res[i] = stringKeys(e)
+		}
+		return res
+	default:
+		return v
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package diff
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	d := Diff{}
+	d.add(Changed, "resources[repo].source", map[interface{}]interface{}{"branch": "master"}, map[interface{}]interface{}{"branch": "develop"})
+	d.add(Added, "jobs[e2e]", nil, map[interface{}]interface{}{"name": "e2e"})
+	d.add(Removed, "jobs[lint]", map[interface{}]interface{}{"name": "lint"}, nil)
+	tests := []struct {
+		format string
+		diff   Diff
+		want   string
+	}{
+		{
+			format: TextFormat,
+			diff:   d,
+			want: `~ resources[repo].source: {"branch":"master"} -> {"branch":"develop"}
++ jobs[e2e]
+- jobs[lint]
+`,
+		},
+		{
+			format: JSONFormat,
+			diff:   d,
+			want: `{
+  "changes": [
+    {
+      "kind": "changed",
+      "path": "resources[repo].source",
+      "before": {
+        "branch": "master"
+      },
+      "after": {
+        "branch": "develop"
+      }
+    },
+    {
+      "kind": "added",
+      "path": "jobs[e2e]",
+      "after": {
+        "name": "e2e"
+      }
+    },
+    {
+      "kind": "removed",
+      "path": "jobs[lint]",
+      "before": {
+        "name": "lint"
+      }
+    }
+  ]
+}`,
+		},
+		{
+			format: JSONFormat,
+			want: `{
+  "changes": []
+}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := tt.diff.Render(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+	if _, err := d.Render("html"); err != UnsupportedFormatError {
+		t.Errorf("got error %v rendering html, want %v", err, UnsupportedFormatError)
+	}
+}
@@ -6,7 +6,11 @@ Sniperkit-Bot
 package error
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"regexp"
+	"strconv"
 )
 
 func CheckError(e error) {
@@ -14,3 +18,108 @@ func CheckError(e error) {
 		log.Fatalf("error: %v\n", e)
 	}
 }
+
+var (
+	yamlLineRegexp       = regexp.MustCompile(`line (\d+)`)
+	yamlLinePrefixRegexp = regexp.MustCompile(`line \d+: `)
+)
+
+// Error is returned by the error returning API of the library. On top of the
+// underlying error it records where the offending input was found: the file
+// and line, and a path to the element inside the document, e.g.
+// "jobs[build].plan[2]".
+type Error struct {
+	File string
+	Line int
+	Path string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	res := ""
+	if e.File != "" && e.Line != 0 {
+		res = e.File + ":" + strconv.Itoa(e.Line) + ": "
+	} else if e.File != "" {
+		res = e.File + ": "
+	} else if e.Line != 0 {
+		res = "line " + strconv.Itoa(e.Line) + ": "
+	}
+	if e.Path != "" {
+		res += e.Path + ": "
+	}
+	return res + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func toError(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Err: err}
+}
+
+// Errorf formats an error located at path.
+func Errorf(path string, format string, a ...interface{}) error {
+	return &Error{Path: path, Err: fmt.Errorf(format, a...)}
+}
+
+// WithPath prefixes the document path of err with path.
+func WithPath(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	e := toError(err)
+	res := *e
+	if res.Path == "" {
+		res.Path = path
+	} else if path != "" {
+		if res.Path[0] == '[' {
+			res.Path = path + res.Path
+		} else {
+			res.Path = path + "." + res.Path
+		}
+	}
+	return &res
+}
+
+// WithFile records the file err originates from.
+func WithFile(err error, file string) error {
+	if err == nil {
+		return nil
+	}
+	e := toError(err)
+	res := *e
+	res.File = file
+	return &res
+}
+
+// FromYAML wraps an error returned by the yaml decoder, extracting the line
+// it points at when there is one.
+func FromYAML(err error) error {
+	if err == nil {
+		return nil
+	}
+	e := toError(err)
+	res := *e
+	if m := yamlLineRegexp.FindStringSubmatch(err.Error()); m != nil && res.Line == 0 {
+		res.Line, _ = strconv.Atoi(m[1])
+	}
+	return &res
+}
+
+// FromYAMLFragment wraps an error returned by the yaml decoder on a fragment
+// re-marshalled from a document already parsed, e.g. a step of a job. The
+// lines the decoder points at are lines of the fragment, not of the document,
+// so they are dropped: callers locate the error with WithPath instead.
+func FromYAMLFragment(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return &Error{Err: errors.New(yamlLinePrefixRegexp.ReplaceAllString(err.Error(), ""))}
+}
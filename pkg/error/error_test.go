@@ -0,0 +1,68 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package error
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFromYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		fragment bool
+		want     string
+		line     int
+	}{
+		{
+			name: "line of the document is kept",
+			err:  errors.New("yaml: unmarshal errors:\n  line 12: cannot unmarshal !!map into []interface {}"),
+			want: "line 12: yaml: unmarshal errors:\n  line 12: cannot unmarshal !!map into []interface {}",
+			line: 12,
+		},
+		{
+			name:     "line of a fragment is dropped",
+			err:      errors.New("yaml: unmarshal errors:\n  line 2: cannot unmarshal !!map into []interface {}"),
+			fragment: true,
+			want:     "yaml: unmarshal errors:\n  cannot unmarshal !!map into []interface {}",
+		},
+		{
+			name:     "syntax error of a fragment",
+			err:      errors.New("yaml: line 3: did not find expected key"),
+			fragment: true,
+			want:     "yaml: did not find expected key",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.fragment {
+				err = FromYAMLFragment(tt.err)
+			} else {
+				err = FromYAML(tt.err)
+			}
+			e, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("got %T, want *Error", err)
+			}
+			if e.Line != tt.line {
+				t.Errorf("got line %d, want %d", e.Line, tt.line)
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithPath(t *testing.T) {
+	err := WithPath(WithPath(FromYAMLFragment(errors.New("yaml: line 2: bad")), "[1]"), "jobs[build].plan")
+	want := "jobs[build].plan[1]: yaml: bad"
+	if got := err.Error(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,200 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package graph
+
+import (
+	"sort"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+type NodeKind string
+
+const (
+	JobNode      NodeKind = "job"
+	ResourceNode NodeKind = "resource"
+)
+
+type EdgeKind string
+
+const (
+	// resource -> job
+	GetEdge EdgeKind = "get"
+	// job -> resource
+	PutEdge EdgeKind = "put"
+	// job -> job, through passed constraints
+	PassedEdge EdgeKind = "passed"
+)
+
+type Node struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name"`
+	Kind NodeKind `json:"kind"`
+}
+
+type Edge struct {
+	From string   `json:"from"`
+	To   string   `json:"to"`
+	Kind EdgeKind `json:"kind"`
+	// optional fields
+	Trigger   bool     `json:"trigger,omitempty"`
+	Resources []string `json:"resources,omitempty"`
+}
+
+// Graph is the job/resource dependency graph of a pipeline. Nodes and edges
+// are kept sorted so that every rendering is deterministic.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+	nodes map[string]int
+	edges map[string]int
+}
+
+func NodeID(kind NodeKind, name string) string {
+	return string(kind) + ":" + name
+}
+
+func NewGraph(p pipeline.Pipeline) (*Graph, error) {
+	g := &Graph{
+		nodes: make(map[string]int),
+		edges: make(map[string]int),
+	}
+	for _, r := range p.Resources.Resources {
+		g.addNode(ResourceNode, r.Name)
+	}
+	for _, j := range p.Jobs.Jobs {
+		g.addNode(JobNode, j.Name)
+	}
+	for _, j := range p.Jobs.Jobs {
+		jid := NodeID(JobNode, j.Name)
+		err := j.Walk(func(path string, t job.Type, s interface{}) error {
+			switch t {
+			case job.GetStepType:
+				st, err := job.GetGetStep(s)
+				if err != nil {
+					return err
+				}
+				rid := g.addNode(ResourceNode, st.ResourceName())
+				g.addEdge(rid, jid, GetEdge, st.Trigger, "")
+				for _, passed := range st.Passed {
+					pid := g.addNode(JobNode, passed)
+					g.addEdge(pid, jid, PassedEdge, false, st.ResourceName())
+				}
+			case job.PutStepType:
+				st, err := job.GetPutStep(s)
+				if err != nil {
+					return err
+				}
+				rid := g.addNode(ResourceNode, st.ResourceName())
+				g.addEdge(jid, rid, PutEdge, false, "")
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	g.sort()
+	return g, nil
+}
+
+// JobsOnly returns the subgraph made of jobs and passed edges, i.e. which
+// jobs gate which.
+func (g *Graph) JobsOnly() *Graph {
+	res := &Graph{
+		nodes: make(map[string]int),
+		edges: make(map[string]int),
+	}
+	for _, n := range g.Nodes {
+		if n.Kind == JobNode {
+			res.addNode(n.Kind, n.Name)
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Kind == PassedEdge {
+			for _, r := range e.Resources {
+				res.addEdge(e.From, e.To, e.Kind, false, r)
+			}
+		}
+	}
+	res.sort()
+	return res
+}
+
+func (g *Graph) Node(id string) (Node, bool) {
+	i, ok := g.nodes[id]
+	if !ok {
+		return Node{}, false
+	}
+	return g.Nodes[i], true
+}
+
+// Adjacency returns the outgoing edges of every node, keyed by node id.
+func (g *Graph) Adjacency() map[string][]Edge {
+	res := make(map[string][]Edge)
+	for _, n := range g.Nodes {
+		res[n.ID] = []Edge{}
+	}
+	for _, e := range g.Edges {
+		res[e.From] = append(res[e.From], e)
+	}
+	return res
+}
+
+func (g *Graph) addNode(kind NodeKind, name string) string {
+	id := NodeID(kind, name)
+	if _, ok := g.nodes[id]; !ok {
+		g.nodes[id] = len(g.Nodes)
+		g.Nodes = append(g.Nodes, Node{ID: id, Name: name, Kind: kind})
+	}
+	return id
+}
+
+func (g *Graph) addEdge(from, to string, kind EdgeKind, trigger bool, resource string) {
+	key := from + "\x00" + to + "\x00" + string(kind)
+	i, ok := g.edges[key]
+	if !ok {
+		i = len(g.Edges)
+		g.edges[key] = i
+		g.Edges = append(g.Edges, Edge{From: from, To: to, Kind: kind})
+	}
+	e := &g.Edges[i]
+	e.Trigger = e.Trigger || trigger
+	if resource != "" {
+		for _, r := range e.Resources {
+			if r == resource {
+				return
+			}
+		}
+		e.Resources = append(e.Resources, resource)
+	}
+}
+
+func (g *Graph) sort() {
+	sort.SliceStable(g.Nodes, func(i, j int) bool {
+		if g.Nodes[i].Kind != g.Nodes[j].Kind {
+			return g.Nodes[i].Kind < g.Nodes[j].Kind
+		}
+		return g.Nodes[i].Name < g.Nodes[j].Name
+	})
+	for i, n := range g.Nodes {
+		g.nodes[n.ID] = i
+	}
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		if g.Edges[i].To != g.Edges[j].To {
+			return g.Edges[i].To < g.Edges[j].To
+		}
+		return g.Edges[i].Kind < g.Edges[j].Kind
+	})
+	for i, e := range g.Edges {
+		sort.Strings(g.Edges[i].Resources)
+		g.edges[e.From+"\x00"+e.To+"\x00"+string(e.Kind)] = i
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/pipeline"
+)
+
+// testPipeline gates deploy on unit through passed constraints on two
+// resources, one of them renamed in deploy and triggering it.
+const testPipeline = `
+resources:
+- name: repo
+  type: git
+- name: img
+  type: registry-image
+jobs:
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+  - put: img
+- name: deploy
+  plan:
+  - get: repo
+    passed: [unit]
+  - get: image
+    resource: img
+    passed: [unit]
+    trigger: true
+`
+
+// reorderedPipeline is testPipeline with its resources, jobs and steps in
+// another order.
+const reorderedPipeline = `
+jobs:
+- name: deploy
+  plan:
+  - in_parallel:
+    - get: image
+      resource: img
+      passed: [unit]
+      trigger: true
+    - get: repo
+      passed: [unit]
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+  - put: img
+resources:
+- name: img
+  type: registry-image
+- name: repo
+  type: git
+`
+
+func TestNewGraphOrder(t *testing.T) {
+	want := []string{"job:deploy", "job:unit", "resource:img", "resource:repo"}
+	wantEdges := []string{
+		"job:unit -passed-> job:deploy",
+		"job:unit -put-> resource:img",
+		"resource:img -get-> job:deploy",
+		"resource:repo -get-> job:deploy",
+		"resource:repo -get-> job:unit",
+	}
+	var renders []string
+	for _, data := range []string{testPipeline, reorderedPipeline} {
+		g := testGraph(t, data)
+		var nodes, edges []string
+		for _, n := range g.Nodes {
+			nodes = append(nodes, n.ID)
+		}
+		for _, e := range g.Edges {
+			edges = append(edges, e.From+" -"+string(e.Kind)+"-> "+e.To)
+		}
+		assertStrings(t, "nodes", nodes, want)
+		assertStrings(t, "edges", edges, wantEdges)
+		renders = append(renders, g.DOT())
+	}
+	if renders[0] != renders[1] {
+		t.Errorf("reordering the pipeline changes its rendering:\n%s\n%s", renders[0], renders[1])
+	}
+}
+
+func TestJobsOnly(t *testing.T) {
+	g := testGraph(t, testPipeline).JobsOnly()
+	var nodes []string
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n.ID)
+	}
+	assertStrings(t, "nodes", nodes, []string{"job:deploy", "job:unit"})
+	if len(g.Edges) != 1 {
+		t.Fatalf("got edges %+v, want the passed edge only", g.Edges)
+	}
+	e := g.Edges[0]
+	if e.From != "job:unit" || e.To != "job:deploy" || e.Kind != PassedEdge {
+		t.Errorf("got edge %+v", e)
+	}
+	assertStrings(t, "resources", e.Resources, []string{"img", "repo"})
+}
+
+func testGraph(t *testing.T, data string) *Graph {
+	t.Helper()
+	p, err := pipeline.ParsePipeline(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewGraph(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func assertStrings(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got %s %q, want %q", what, got, want)
+	}
+}
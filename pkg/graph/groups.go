@@ -0,0 +1,202 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package graph
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/group"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+const (
+	// ComponentsStrategy groups the jobs connected by passed constraints.
+	ComponentsStrategy = "components"
+	// PrefixStrategy groups jobs by the part of their name before Separator.
+	PrefixStrategy = "prefix"
+	// RegexStrategy groups jobs by the part of their name matched by the
+	// first of Patterns matching.
+	RegexStrategy = "regex"
+
+	DefaultGroupSeparator = "-"
+	DefaultGroup          = "ungrouped"
+	AllGroup              = "all"
+
+	UnsupportedStrategyError types.InternalError = "unsupported group strategy"
+)
+
+type GroupOptions struct {
+	Strategy string
+	// Separator ends the prefix of job names with the prefix strategy.
+	Separator string
+	// Patterns name the group of the jobs they match with the regex strategy:
+	// the submatch named group, the first submatch or the whole match. Jobs
+	// matching none of them go to Default.
+	Patterns []string
+	Default  string
+	// All adds a first group holding every job, named AllGroup. Jobs grouped
+	// under that name by the strategy are reported as errors.
+	All bool
+}
+
+// Groups derives groups from the graph, so that every job belongs to a group
+// and every group lists the resources its jobs get or put. Groups, their jobs
+// and their resources are sorted by name.
+func (g *Graph) Groups(opts GroupOptions) (group.Groups, error) {
+	var name func(string) string
+	switch opts.Strategy {
+	case ComponentsStrategy, "":
+		name = g.componentNames()
+	case PrefixStrategy:
+		sep := opts.Separator
+		if sep == "" {
+			sep = DefaultGroupSeparator
+		}
+		name = func(j string) string {
+			return strings.SplitN(j, sep, 2)[0]
+		}
+	case RegexStrategy:
+		f, err := regexNames(opts.Patterns, opts.Default)
+		if err != nil {
+			return group.Groups{}, err
+		}
+		name = f
+	default:
+		return group.Groups{}, UnsupportedStrategyError
+	}
+
+	jobs := make(map[string][]string)
+	var all []string
+	for _, n := range g.Nodes {
+		if n.Kind != JobNode {
+			continue
+		}
+		gn := name(n.Name)
+		jobs[gn] = append(jobs[gn], n.Name)
+		all = append(all, n.Name)
+	}
+	var names []string
+	for gn := range jobs {
+		names = append(names, gn)
+	}
+	sort.Strings(names)
+
+	res := group.Groups{}
+	if opts.All {
+		if _, ok := jobs[AllGroup]; ok {
+			return res, fmt.Errorf("group %s of jobs %s collides with the group of every job", AllGroup, strings.Join(jobs[AllGroup], ", "))
+		}
+		res.Groups = append(res.Groups, group.Group{Name: AllGroup, Jobs: all, Resources: g.resourcesOf(all)})
+	}
+	for _, gn := range names {
+		res.Groups = append(res.Groups, group.Group{Name: gn, Jobs: jobs[gn], Resources: g.resourcesOf(jobs[gn])})
+	}
+	return res, nil
+}
+
+// componentNames names every job after the connected component of the
+// passed graph it belongs to, i.e. after the first job by name of the
+// component without passed constraints.
+func (g *Graph) componentNames() func(string) string {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(n string) string {
+		if p, ok := parent[n]; ok && p != n {
+			parent[n] = find(p)
+			return parent[n]
+		}
+		return n
+	}
+	hasPassed := make(map[string]bool)
+	for _, e := range g.Edges {
+		if e.Kind != PassedEdge {
+			continue
+		}
+		hasPassed[e.To] = true
+		a, b := find(e.From), find(e.To)
+		if a != b {
+			parent[b] = a
+		}
+	}
+	names := make(map[string]string)
+	// nodes are sorted: the first root of a component names it, the first
+	// job when the component is a cycle
+	for _, roots := range []bool{true, false} {
+		for _, n := range g.Nodes {
+			if n.Kind != JobNode || (roots && hasPassed[n.ID]) {
+				continue
+			}
+			if _, ok := names[find(n.ID)]; !ok {
+				names[find(n.ID)] = n.Name
+			}
+		}
+	}
+	return func(j string) string {
+		return names[find(NodeID(JobNode, j))]
+	}
+}
+
+func regexNames(patterns []string, def string) (func(string) string, error) {
+	if def == "" {
+		def = DefaultGroup
+	}
+	var regexps []*regexp.Regexp
+	for _, p := range patterns {
+		r, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group pattern %q: %v", p, err)
+		}
+		regexps = append(regexps, r)
+	}
+	return func(j string) string {
+		for _, r := range regexps {
+			m := r.FindStringSubmatch(j)
+			if m == nil {
+				continue
+			}
+			for i, n := range r.SubexpNames() {
+				if n == "group" && m[i] != "" {
+					return m[i]
+				}
+			}
+			if len(m) > 1 && m[1] != "" {
+				return m[1]
+			}
+			return m[0]
+		}
+		return def
+	}, nil
+}
+
+// resourcesOf returns the resources the jobs get or put, sorted.
+func (g *Graph) resourcesOf(jobs []string) []string {
+	ids := make(map[string]bool)
+	for _, j := range jobs {
+		ids[NodeID(JobNode, j)] = true
+	}
+	seen := make(map[string]bool)
+	var res []string
+	for _, e := range g.Edges {
+		var rid string
+		switch {
+		case e.Kind == GetEdge && ids[e.To]:
+			rid = e.From
+		case e.Kind == PutEdge && ids[e.From]:
+			rid = e.To
+		default:
+			continue
+		}
+		if n, ok := g.Node(rid); ok && !seen[n.Name] {
+			seen[n.Name] = true
+			res = append(res, n.Name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,157 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+const groupsPipeline = `
+resources:
+- name: repo
+  type: git
+- name: img
+  type: registry-image
+jobs:
+- name: unit
+  plan:
+  - get: repo
+  - put: img
+- name: deploy-prod
+  plan:
+  - get: img
+    passed: [unit]
+- name: deploy-staging
+  plan:
+  - get: img
+    passed: [unit]
+- name: lint
+  plan:
+  - get: repo
+`
+
+func TestGroups(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		opts GroupOptions
+		want []string
+		err  string
+	}{
+		{
+			name: "components named after their first root",
+			opts: GroupOptions{Strategy: ComponentsStrategy},
+			want: []string{
+				"lint: lint; repo",
+				"unit: deploy-prod, deploy-staging, unit; img, repo",
+			},
+		},
+		{
+			name: "prefix",
+			opts: GroupOptions{Strategy: PrefixStrategy, All: true},
+			want: []string{
+				"all: deploy-prod, deploy-staging, lint, unit; img, repo",
+				"deploy: deploy-prod, deploy-staging; img",
+				"lint: lint; repo",
+				"unit: unit; img, repo",
+			},
+		},
+		{
+			name: "prefix with another separator",
+			opts: GroupOptions{Strategy: PrefixStrategy, Separator: "-s"},
+			want: []string{
+				"deploy: deploy-staging; img",
+				"deploy-prod: deploy-prod; img",
+				"lint: lint; repo",
+				"unit: unit; img, repo",
+			},
+		},
+		{
+			name: "regex submatches, the named one first",
+			opts: GroupOptions{
+				Strategy: RegexStrategy,
+				Patterns: []string{`^deploy-(staging)$`, `^(?:deploy)-(?P<env>x)?(?P<group>prod)$`, `^(lint)$`},
+			},
+			want: []string{
+				"lint: lint; repo",
+				"prod: deploy-prod; img",
+				"staging: deploy-staging; img",
+				"ungrouped: unit; img, repo",
+			},
+		},
+		{
+			name: "regex default group",
+			opts: GroupOptions{Strategy: RegexStrategy, Patterns: []string{`^deploy`}, Default: "build"},
+			want: []string{
+				"build: lint, unit; img, repo",
+				"deploy: deploy-prod, deploy-staging; img",
+			},
+		},
+		{
+			name: "invalid pattern",
+			opts: GroupOptions{Strategy: RegexStrategy, Patterns: []string{`^(deploy`}},
+			err:  "invalid group pattern",
+		},
+		{
+			name: "unsupported strategy",
+			opts: GroupOptions{Strategy: "owner"},
+			err:  string(UnsupportedStrategyError),
+		},
+		{
+			name: "prefix group named all",
+			data: `
+jobs:
+- name: all-tests
+  plan: []
+- name: unit
+  plan: []
+`,
+			opts: GroupOptions{Strategy: PrefixStrategy},
+			want: []string{"all: all-tests; ", "unit: unit; "},
+		},
+		{
+			name: "prefix group named all along every job",
+			data: `
+jobs:
+- name: all-tests
+  plan: []
+- name: unit
+  plan: []
+`,
+			opts: GroupOptions{Strategy: PrefixStrategy, All: true},
+			err:  "group all of jobs all-tests collides with the group of every job",
+		},
+		{
+			name: "regex default group named all along every job",
+			opts: GroupOptions{Strategy: RegexStrategy, Patterns: []string{`^deploy`}, Default: AllGroup, All: true},
+			err:  "group all of jobs lint, unit collides with the group of every job",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.data
+			if data == "" {
+				data = groupsPipeline
+			}
+			groups, err := testGraph(t, data).Groups(tt.opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, g := range groups.Groups {
+				got = append(got, g.Name+": "+strings.Join(g.Jobs, ", ")+"; "+strings.Join(g.Resources, ", "))
+			}
+			assertStrings(t, "groups", got, tt.want)
+		})
+	}
+}
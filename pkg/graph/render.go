@@ -0,0 +1,130 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+const (
+	DOTFormat     = "dot"
+	MermaidFormat = "mermaid"
+	JSONFormat    = "json"
+
+	UnsupportedFormatError types.InternalError = "unsupported graph format"
+)
+
+func (g *Graph) Render(format string) (string, error) {
+	switch format {
+	case DOTFormat:
+		return g.DOT(), nil
+	case MermaidFormat:
+		return g.Mermaid(), nil
+	case JSONFormat:
+		b, err := g.JSON()
+		if err != nil {
+			return "", err
+		}
+		return string(b[:]), nil
+	default:
+		return "", UnsupportedFormatError
+	}
+}
+
+// DOT renders the graph in Graphviz format. Jobs are boxes, resources are
+// ellipses and non-triggering gets are dashed, like in the Concourse UI.
+func (g *Graph) DOT() string {
+	var b bytes.Buffer
+	b.WriteString("digraph pipeline {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, n := range g.Nodes {
+		shape := "box"
+		if n.Kind == ResourceNode {
+			shape = "ellipse"
+		}
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", strconv.Quote(n.ID), strconv.Quote(n.Name), shape)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Kind == GetEdge && !e.Trigger {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(e.Resources) != 0 {
+			attrs = append(attrs, "label="+strconv.Quote(strings.Join(e.Resources, "\\n")))
+		}
+		fmt.Fprintf(&b, "  %s -> %s", strconv.Quote(e.From), strconv.Quote(e.To))
+		if len(attrs) != 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (g *Graph) Mermaid() string {
+	var b bytes.Buffer
+	b.WriteString("graph LR\n")
+	for _, n := range g.Nodes {
+		label := strconv.Quote(n.Name)
+		if n.Kind == ResourceNode {
+			fmt.Fprintf(&b, "  %s([%s])\n", mermaidID(n.ID), label)
+		} else {
+			fmt.Fprintf(&b, "  %s[%s]\n", mermaidID(n.ID), label)
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Kind == GetEdge && !e.Trigger {
+			arrow = "-.->"
+		}
+		if len(e.Resources) != 0 {
+			arrow += "|" + strconv.Quote(strings.Join(e.Resources, ", ")) + "|"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", mermaidID(e.From), arrow, mermaidID(e.To))
+	}
+	return b.String()
+}
+
+// mermaid node ids can only hold alphanumerics and underscores, every other
+// character is escaped with its code point to keep ids unique.
+func mermaidID(id string) string {
+	var b bytes.Buffer
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			b.WriteRune(c)
+		default:
+			fmt.Fprintf(&b, "_%x_", c)
+		}
+	}
+	return b.String()
+}
+
+type jsonGraph struct {
+	Nodes     []Node            `json:"nodes"`
+	Adjacency map[string][]Edge `json:"adjacency"`
+}
+
+// JSON renders the graph as a list of nodes plus an adjacency list holding
+// the outgoing edges of every node.
+func (g *Graph) JSON() ([]byte, error) {
+	nodes := g.Nodes
+	if nodes == nil {
+		nodes = []Node{}
+	}
+	return json.MarshalIndent(jsonGraph{
+		Nodes:     nodes,
+		Adjacency: g.Adjacency(),
+	}, "", "  ")
+}
@@ -0,0 +1,104 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package graph
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: DOTFormat,
+			want: `digraph pipeline {
+  rankdir=LR;
+  "job:deploy" [label="deploy", shape=box];
+  "job:unit" [label="unit", shape=box];
+  "resource:img" [label="img", shape=ellipse];
+  "resource:repo" [label="repo", shape=ellipse];
+  "job:unit" -> "job:deploy" [label="img\\nrepo"];
+  "job:unit" -> "resource:img";
+  "resource:img" -> "job:deploy";
+  "resource:repo" -> "job:deploy" [style=dashed];
+  "resource:repo" -> "job:unit";
+}
+`,
+		},
+		{
+			format: MermaidFormat,
+			want: `graph LR
+  job_3a_deploy["deploy"]
+  job_3a_unit["unit"]
+  resource_3a_img(["img"])
+  resource_3a_repo(["repo"])
+  job_3a_unit -->|"img, repo"| job_3a_deploy
+  job_3a_unit --> resource_3a_img
+  resource_3a_img --> job_3a_deploy
+  resource_3a_repo -.-> job_3a_deploy
+  resource_3a_repo --> job_3a_unit
+`,
+		},
+	}
+	g := testGraph(t, testPipeline)
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := g.Render(tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+	if _, err := g.Render("svg"); err != UnsupportedFormatError {
+		t.Errorf("got error %v rendering svg, want %v", err, UnsupportedFormatError)
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	want := `{
+  "nodes": [
+    {"id": "job:deploy", "name": "deploy", "kind": "job"},
+    {"id": "job:unit", "name": "unit", "kind": "job"},
+    {"id": "resource:img", "name": "img", "kind": "resource"},
+    {"id": "resource:repo", "name": "repo", "kind": "resource"}
+  ],
+  "adjacency": {
+    "job:deploy": [],
+    "job:unit": [
+      {"from": "job:unit", "to": "job:deploy", "kind": "passed", "resources": ["img", "repo"]},
+      {"from": "job:unit", "to": "resource:img", "kind": "put"}
+    ],
+    "resource:img": [
+      {"from": "resource:img", "to": "job:deploy", "kind": "get", "trigger": true}
+    ],
+    "resource:repo": [
+      {"from": "resource:repo", "to": "job:deploy", "kind": "get"},
+      {"from": "resource:repo", "to": "job:unit", "kind": "get", "trigger": true}
+    ]
+  }
+}`
+	got, err := testGraph(t, testPipeline).Render(JSONFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g, w interface{}
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -15,6 +15,38 @@ type Groups struct {
 	Groups []Group `yaml:"groups"`
 }
 
+func (g *Groups) String() string {
+	b, err := yaml.Marshal(*g)
+	berror.CheckError(err)
+	return string(b[:])
+}
+
+// UpdateWith merges groups by name, unioning their jobs and resources. Groups
+// only defined in n are appended.
+func (g *Groups) UpdateWith(n Groups) Groups {
+	nm := make(map[string]Group)
+	for _, ng := range n.Groups {
+		nm[ng.Name] = ng
+	}
+	seen := make(map[string]bool)
+	var res []Group
+	for _, gg := range g.Groups {
+		seen[gg.Name] = true
+		if ng, ok := nm[gg.Name]; ok {
+			res = append(res, gg.UpdateWith(ng))
+		} else {
+			res = append(res, gg)
+		}
+	}
+	for _, ng := range n.Groups {
+		if !seen[ng.Name] {
+			seen[ng.Name] = true
+			res = append(res, ng)
+		}
+	}
+	return Groups{res}
+}
+
 type Group struct {
 	Name string `yaml:"name"`
 	// optional fields
@@ -28,9 +60,71 @@ func (g *Group) String() string {
 	return string(b[:])
 }
 
+func (g *Group) UpdateWith(n Group) Group {
+	return Group{
+		Name:      g.Name,
+		Jobs:      union(g.Jobs, n.Jobs),
+		Resources: union(g.Resources, n.Resources),
+	}
+}
+
+func union(a, b []string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	for _, l := range [][]string{a, b} {
+		for _, v := range l {
+			if !seen[v] {
+				seen[v] = true
+				res = append(res, v)
+			}
+		}
+	}
+	return res
+}
+
 func GetGroupsFromString(data string) Groups {
-	g := Groups{}
-	err := yaml.Unmarshal([]byte(data), &g)
+	g, err := ParseGroups(data)
 	berror.CheckError(err)
 	return g
 }
+
+// ParseGroups is the error returning counterpart of GetGroupsFromString.
+func ParseGroups(data string) (Groups, error) {
+	g := Groups{}
+	err := yaml.Unmarshal([]byte(data), &g)
+	if err != nil {
+		return g, berror.FromYAML(err)
+	}
+	return g, nil
+}
+
+// Prune drops the jobs and resources the groups reference that are not
+// defined, and the groups left without jobs.
+func (g *Groups) Prune(jobs, resources []string) Groups {
+	res := Groups{}
+	for _, gg := range g.Groups {
+		p := Group{
+			Name:      gg.Name,
+			Jobs:      intersect(gg.Jobs, jobs),
+			Resources: intersect(gg.Resources, resources),
+		}
+		if len(p.Jobs) != 0 {
+			res.Groups = append(res.Groups, p)
+		}
+	}
+	return res
+}
+
+func intersect(a, b []string) []string {
+	in := make(map[string]bool)
+	for _, v := range b {
+		in[v] = true
+	}
+	var res []string
+	for _, v := range a {
+		if in[v] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
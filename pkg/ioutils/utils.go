@@ -8,14 +8,24 @@ package ioutils
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
 )
 
-func ReadFile(name string) string {
+// LoadFile is the error returning counterpart of ReadFile.
+func LoadFile(name string) (string, error) {
 	dat, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return string(dat), nil
+}
+
+func ReadFile(name string) string {
+	dat, err := LoadFile(name)
 	berror.CheckError(err)
-	return string(dat)
+	return dat
 }
 
 func ReadFileDefaultStdin(name string) string {
@@ -29,16 +39,44 @@ func ReadFileDefaultStdin(name string) string {
 	return ""
 }
 
-func CreateDirIfNotExist(dir string) {
+// EnsureDir is the error returning counterpart of CreateDirIfNotExist.
+func EnsureDir(dir string) error {
 	if _, err := os.Stat(dir); os.IsNotExist(err) {
-		err = os.MkdirAll(dir, 0755)
-		berror.CheckError(err)
+		return os.MkdirAll(dir, 0755)
 	}
+	return nil
 }
 
-func CreateFileIfNotExist(filename string) {
+func CreateDirIfNotExist(dir string) {
+	berror.CheckError(EnsureDir(dir))
+}
+
+// EnsureFile is the error returning counterpart of CreateFileIfNotExist.
+func EnsureFile(filename string) error {
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		_, err = os.Create(filename)
-		berror.CheckError(err)
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	return nil
+}
+
+func CreateFileIfNotExist(filename string) {
+	berror.CheckError(EnsureFile(filename))
+}
+
+// LoadOrCreateFile reads filename, creating it and its parent directories
+// first when they do not exist.
+func LoadOrCreateFile(filename string) (string, error) {
+	err := EnsureDir(filepath.Dir(filename))
+	if err != nil {
+		return "", err
+	}
+	err = EnsureFile(filename)
+	if err != nil {
+		return "", err
 	}
+	return LoadFile(filename)
 }
@@ -0,0 +1,380 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+)
+
+// Artifact is produced by a step of a job: get and put steps produce an
+// artifact named after the step, tasks their outputs, as renamed by their
+// output_mapping.
+type Artifact struct {
+	Name string
+	// path of the producing step inside the job, e.g. "plan[0]"
+	Step string
+	Type Type
+}
+
+// ArtifactInput is an artifact consumed by a step of a job.
+type ArtifactInput struct {
+	Name string
+	Step string
+	// what the step consumes the artifact for, e.g. "input src" or "file"
+	Use      string
+	Optional bool
+	// path of the step producing the artifact, empty when no earlier step
+	// does
+	Producer string
+}
+
+// ArtifactStep lists the artifacts available to a step when it runs.
+type ArtifactStep struct {
+	Step      string
+	Type      Type
+	Available []string
+}
+
+// ArtifactFlow follows the artifacts through the plan of a job, in the order
+// the steps run.
+type ArtifactFlow struct {
+	Steps     []ArtifactStep
+	Artifacts []Artifact
+	Inputs    []ArtifactInput
+	// Problems are errors located by their path inside the job, e.g. inputs
+	// no earlier step produces or mappings of unknown inputs and outputs.
+	Problems []error
+	// Warnings are the put params that look like paths into artifacts no
+	// earlier step produces, e.g. "application/json", which may be values.
+	Warnings []error
+}
+
+// Unused returns the artifacts of get steps and tasks no step consumes. The
+// artifacts put steps fetch back are left out, puts producing them anyway.
+func (f *ArtifactFlow) Unused() []Artifact {
+	used := make(map[Artifact]bool)
+	for _, i := range f.Inputs {
+		for _, a := range f.Artifacts {
+			if a.Name == i.Name && a.Step == i.Producer {
+				used[a] = true
+			}
+		}
+	}
+	var res []Artifact
+	for _, a := range f.Artifacts {
+		if a.Type != PutStepType && !used[a] {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+// PathParams tells whether param key of the put steps of resource holds
+// paths inside artifacts, e.g. the file of an s3 resource.
+type PathParams func(resource, key string) bool
+
+type artifactFlow struct {
+	resolver TaskConfigResolver
+	paths    PathParams
+	res      ArtifactFlow
+}
+
+// ArtifactFlow analyzes the artifacts produced and consumed by the steps of
+// the job. Steps of in_parallel and aggregate steps do not see the artifacts
+// of their siblings, steps of do and try steps and hooks see the artifacts of
+// the steps run before them. The params of put steps consume the artifacts
+// they name or point into, e.g. "bin/*.tgz", when an earlier step produces
+// the artifact or paths tells the param holds paths. Other values looking like
+// paths are only reported as warnings. Tasks defined in a file are only
+// checked when r resolves their config.
+func (j *Job) ArtifactFlow(r TaskConfigResolver, paths PathParams) (ArtifactFlow, error) {
+	f := &artifactFlow{resolver: r, paths: paths}
+	available := make(map[string]string)
+	err := f.steps("plan", j.Plan, available)
+	if err != nil {
+		return f.res, err
+	}
+	err = f.hooks("", j.StepHooks, available)
+	return f.res, err
+}
+
+func (f *artifactFlow) steps(prefix string, steps []interface{}, available map[string]string) error {
+	for i, s := range steps {
+		err := f.step(fmt.Sprintf("%s[%d]", prefix, i), s, available)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *artifactFlow) parallel(prefix string, steps []interface{}, available map[string]string) error {
+	var scopes []map[string]string
+	for i, s := range steps {
+		scope := make(map[string]string)
+		for k, v := range available {
+			scope[k] = v
+		}
+		err := f.step(fmt.Sprintf("%s[%d]", prefix, i), s, scope)
+		if err != nil {
+			return err
+		}
+		scopes = append(scopes, scope)
+	}
+	for _, scope := range scopes {
+		for k, v := range scope {
+			available[k] = v
+		}
+	}
+	return nil
+}
+
+func (f *artifactFlow) step(path string, s interface{}, available map[string]string) error {
+	t, err := TypeOf(s)
+	if err != nil {
+		return berror.WithPath(err, path)
+	}
+	var names []string
+	for n := range available {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	f.res.Steps = append(f.res.Steps, ArtifactStep{Step: path, Type: t, Available: names})
+	switch t {
+	case GetStepType:
+		st, err := GetGetStep(s)
+		if err != nil {
+			return berror.WithPath(err, path)
+		}
+		f.produce(st.Get, path, t, available)
+	case PutStepType:
+		st, err := GetPutStep(s)
+		if err != nil {
+			return berror.WithPath(err, path)
+		}
+		f.putParams(st.ResourceName(), st.Params, path, available)
+		f.produce(st.Put, path, t, available)
+	case TaskStepType:
+		if err := f.task(path, s, available); err != nil {
+			return err
+		}
+	case SetPipelineStepType:
+		st, err := GetSetPipelineStep(s)
+		if err != nil {
+			return berror.WithPath(err, path)
+		}
+		f.consume(FileArtifact(st.File), path, "file", false, available)
+	case LoadVarStepType:
+		st, err := GetLoadVarStep(s)
+		if err != nil {
+			return berror.WithPath(err, path)
+		}
+		f.consume(FileArtifact(st.File), path, "file", false, available)
+	case DoStepType, TryStepType:
+		key, children, err := Children(s)
+		if err != nil {
+			return berror.WithPath(err, path)
+		}
+		if err := f.steps(path+"."+key, children, available); err != nil {
+			return err
+		}
+	case AggregateStepType, InParallelStepType:
+		key, children, err := Children(s)
+		if err != nil {
+			return berror.WithPath(err, path)
+		}
+		if err := f.parallel(path+"."+key, children, available); err != nil {
+			return err
+		}
+	}
+	d, err := yaml.Marshal(&s)
+	if err != nil {
+		return berror.WithPath(err, path)
+	}
+	st := Step{}
+	err = yaml.Unmarshal(d, &st)
+	if err != nil {
+		return berror.WithPath(berror.FromYAMLFragment(err), path)
+	}
+	return f.hooks(path, st.StepHooks, available)
+}
+
+func (f *artifactFlow) hooks(path string, h StepHooks, available map[string]string) error {
+	hooks := []struct {
+		key  string
+		step interface{}
+	}{
+		{"on_success", h.OnSuccess},
+		{"on_failure", h.OnFailure},
+		{"on_error", h.OnError},
+		{"on_abort", h.OnAbort},
+		{"ensure", h.Ensure},
+	}
+	for _, hook := range hooks {
+		if hook.step == nil {
+			continue
+		}
+		p := hook.key
+		if path != "" {
+			p = path + "." + hook.key
+		}
+		err := f.step(p, hook.step, available)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *artifactFlow) task(path string, s interface{}, available map[string]string) error {
+	st, err := GetTaskStep(s)
+	if err != nil {
+		return berror.WithPath(err, path)
+	}
+	if st.File != "" {
+		f.consume(FileArtifact(st.File), path, "file", false, available)
+	}
+	c, found, err := st.GetConfig(f.resolver)
+	if err != nil {
+		f.problem(berror.WithPath(err, path))
+		return nil
+	}
+	if !found {
+		if st.File != "" && f.resolver != nil {
+			f.problem(berror.Errorf(path+".file", "could not find task config %s", st.File))
+		}
+		// without the config only the mappings tell the artifacts
+		for _, k := range mappingKeys(st.InputMapping) {
+			f.consume(fmt.Sprint(st.InputMapping[k]), path, "input "+k, false, available)
+		}
+		for _, k := range mappingKeys(st.OutputMapping) {
+			f.produce(fmt.Sprint(st.OutputMapping[k]), path, TaskStepType, available)
+		}
+		return nil
+	}
+
+	cpath := path + ".config"
+	if st.Config == nil {
+		cpath = path + ".file"
+	}
+	if err := c.Validate(); err != nil {
+		f.problem(berror.WithPath(err, cpath))
+	}
+	for _, k := range mappingKeys(st.InputMapping) {
+		if _, ok := c.Input(k); !ok {
+			f.problem(berror.Errorf(path+".input_mapping", "%q is not an input of the task", k))
+		}
+	}
+	for _, k := range mappingKeys(st.OutputMapping) {
+		if _, ok := c.Output(k); !ok {
+			f.problem(berror.Errorf(path+".output_mapping", "%q is not an output of the task", k))
+		}
+	}
+	for _, i := range c.Inputs {
+		name := i.Name
+		if m, ok := st.InputMapping[i.Name]; ok {
+			name = fmt.Sprint(m)
+		}
+		f.consume(name, path, "input "+i.Name, i.Optional, available)
+	}
+	for _, o := range c.Outputs {
+		name := o.Name
+		if m, ok := st.OutputMapping[o.Name]; ok {
+			name = fmt.Sprint(m)
+		}
+		f.produce(name, path, TaskStepType, available)
+	}
+	return nil
+}
+
+// putParams consumes the artifacts the string values of the params of a put
+// step to resource refer to.
+func (f *artifactFlow) putParams(resource string, params interface{}, step string, available map[string]string) {
+	p, ok := params.(map[interface{}]interface{})
+	if !ok {
+		return
+	}
+	for _, k := range mappingKeys(p) {
+		path := f.paths != nil && f.paths(resource, k)
+		f.params("params."+k, p[k], path, step, available)
+	}
+}
+
+// params consumes the artifacts the string values of params refer to: the
+// artifact a relative path points into, or an artifact named by the value.
+// Values are only taken as paths when path is set or when an earlier step
+// produces the artifact they point into.
+func (f *artifactFlow) params(key string, params interface{}, path bool, step string, available map[string]string) {
+	switch p := params.(type) {
+	case map[interface{}]interface{}:
+		for _, k := range mappingKeys(p) {
+			f.params(key+"."+k, p[k], path, step, available)
+		}
+	case []interface{}:
+		for i, e := range p {
+			f.params(fmt.Sprintf("%s[%d]", key, i), e, path, step, available)
+		}
+	case string:
+		if p == "" || strings.ContainsAny(p, " \t\n") || strings.Contains(p, "://") || strings.HasPrefix(p, "/") {
+			return
+		}
+		name := FileArtifact(p)
+		if name == "." || name == ".." {
+			return
+		}
+		if _, ok := available[name]; ok || path {
+			f.consume(name, step, key, false, available)
+		} else if strings.Contains(p, "/") && !strings.Contains(name, "((") {
+			f.res.Warnings = append(f.res.Warnings, berror.Errorf(step, "%s: %q may point into artifact %q, which no earlier step produces", key, p, name))
+		}
+	}
+}
+
+func (f *artifactFlow) produce(name, step string, t Type, available map[string]string) {
+	if name == "" {
+		return
+	}
+	available[name] = step
+	f.res.Artifacts = append(f.res.Artifacts, Artifact{Name: name, Step: step, Type: t})
+}
+
+// consume records the use of artifact name, unless the name is only known
+// once interpolated, e.g. by an across step.
+func (f *artifactFlow) consume(name, step, use string, optional bool, available map[string]string) {
+	if name == "" || strings.Contains(name, "((") {
+		return
+	}
+	producer := available[name]
+	f.res.Inputs = append(f.res.Inputs, ArtifactInput{
+		Name:     name,
+		Step:     step,
+		Use:      use,
+		Optional: optional,
+		Producer: producer,
+	})
+	if producer == "" && !optional {
+		f.problem(berror.Errorf(step, "%s: no earlier step produces artifact %q", use, name))
+	}
+}
+
+func (f *artifactFlow) problem(err error) {
+	f.res.Problems = append(f.res.Problems, err)
+}
+
+func mappingKeys(m map[interface{}]interface{}) []string {
+	var res []string
+	for k := range m {
+		res = append(res, fmt.Sprint(k))
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,160 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestArtifactFlow(t *testing.T) {
+	s3Paths := func(resource, key string) bool {
+		return resource == "bucket" && key == "file"
+	}
+	tests := []struct {
+		name     string
+		job      string
+		paths    PathParams
+		inputs   []string
+		problems []string
+		warnings []string
+	}{
+		{
+			name: "task input produced by a get",
+			job: `
+plan:
+- get: repo
+- task: unit
+  config:
+    platform: linux
+    run: {path: make}
+    inputs: [{name: repo}]
+`,
+			inputs: []string{"plan[1] repo from plan[0]"},
+		},
+		{
+			name: "task input produced by no step",
+			job: `
+plan:
+- task: unit
+  config:
+    platform: linux
+    run: {path: make}
+    inputs: [{name: repo}, {name: cache, optional: true}]
+`,
+			inputs:   []string{"plan[0] repo", "plan[0] cache"},
+			problems: []string{`plan[0]: input repo: no earlier step produces artifact "repo"`},
+		},
+		{
+			name: "mapped inputs and unknown outputs",
+			job: `
+plan:
+- get: source
+- task: build
+  input_mapping: {repo: source}
+  output_mapping: {dist: bin}
+  config:
+    platform: linux
+    run: {path: make}
+    inputs: [{name: repo}]
+    outputs: [{name: out}]
+`,
+			inputs:   []string{"plan[1] source from plan[0]"},
+			problems: []string{`plan[1].output_mapping: "dist" is not an output of the task`},
+		},
+		{
+			name: "put params that are values",
+			job: `
+plan:
+- put: bucket
+  params: {file: dist/app.tgz, content_type: application/json, acl: private}
+`,
+			paths:    s3Paths,
+			inputs:   []string{"plan[0] dist"},
+			problems: []string{`plan[0]: params.file: no earlier step produces artifact "dist"`},
+			warnings: []string{`plan[0]: params.content_type: "application/json" may point into artifact "application", which no earlier step produces`},
+		},
+		{
+			name: "put params pointing into artifacts",
+			job: `
+plan:
+- get: repo
+- put: img
+  params: {image: repo/img.tar, version: repo}
+`,
+			inputs: []string{"plan[1] repo from plan[0]", "plan[1] repo from plan[0]"},
+		},
+		{
+			name: "siblings of in_parallel do not see each other",
+			job: `
+plan:
+- in_parallel:
+  - get: repo
+  - put: img
+    params: {image: repo/img.tar}
+- put: archive
+  params: {build: repo}
+`,
+			inputs: []string{"plan[1] repo from plan[0].in_parallel[0]"},
+			warnings: []string{
+				`plan[0].in_parallel[1]: params.image: "repo/img.tar" may point into artifact "repo", which no earlier step produces`,
+			},
+		},
+		{
+			name: "single step of try and hooks",
+			job: `
+plan:
+- get: repo
+- try:
+    put: img
+    params: {image: repo/img.tar}
+on_error:
+  put: alert
+  params: {text_file: repo/message}
+`,
+			inputs: []string{"plan[1].try[0] repo from plan[0]", "on_error repo from plan[0]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := parseJob(t, "name: build"+tt.job)
+			flow, err := j.ArtifactFlow(nil, tt.paths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var inputs []string
+			for _, i := range flow.Inputs {
+				in := i.Step + " " + i.Name
+				if i.Producer != "" {
+					in += " from " + i.Producer
+				}
+				inputs = append(inputs, in)
+			}
+			assertStrings(t, "inputs", inputs, tt.inputs)
+			assertStrings(t, "problems", errorStrings(flow.Problems), tt.problems)
+			assertStrings(t, "warnings", errorStrings(flow.Warnings), tt.warnings)
+		})
+	}
+}
+
+func errorStrings(errs []error) []string {
+	var res []string
+	for _, e := range errs {
+		res = append(res, fmt.Sprint(e))
+	}
+	return res
+}
+
+func assertStrings(t *testing.T, what string, got, want []string) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s %q, want %q", what, got, want)
+	}
+}
@@ -6,7 +6,7 @@ Sniperkit-Bot
 package job
 
 import (
-	"log"
+	"fmt"
 
 	yaml "gopkg.in/yaml.v2"
 
@@ -92,60 +92,110 @@ func (j *Job) String() string {
 }
 
 func (j *Job) buildCache() {
-	if j.typeCache == nil {
-		j.typeCache = make(map[Type][]interface{})
+	berror.CheckError(j.BuildCache())
+}
+
+// BuildCache indexes the plan steps by type and name. Step accessors build
+// the index lazily and terminate the process on a malformed plan, call
+// BuildCache first to handle the error instead.
+func (j *Job) BuildCache() error {
+	if j.stepCache != nil && j.typeCache != nil {
+		return nil
 	}
-	if j.stepCache == nil {
-		j.stepCache = make(map[Type]map[string]interface{})
-		for _, p := range j.Plan {
-			s, err := yaml.Marshal(&p)
-			berror.CheckError(err)
-			t, _ := GetType(string(s))
-			if j.stepCache[t] == nil {
-				j.stepCache[t] = make(map[string]interface{})
+	typeCache := make(map[Type][]interface{})
+	stepCache := make(map[Type]map[string]interface{})
+	for i, p := range j.Plan {
+		path := fmt.Sprintf("jobs[%s].plan[%d]", j.Name, i)
+		s, err := yaml.Marshal(&p)
+		if err != nil {
+			return berror.WithPath(err, path)
+		}
+		t, _ := GetType(string(s))
+		if stepCache[t] == nil {
+			stepCache[t] = make(map[string]interface{})
+		}
+		switch t {
+		case PutStepType, GetStepType, TaskStepType, SetPipelineStepType, LoadVarStepType:
+			name, err := GetStepName(p)
+			if err != nil {
+				return berror.WithPath(err, path)
+			}
+			step := p
+			stepCache[t][name] = &step
+			typeCache[t] = append(typeCache[t], &step)
+			continue
+		case DoStepType:
+			tv, err := GetDoStep(p)
+			if err != nil {
+				return berror.WithPath(err, path)
+			}
+			typeCache[t] = append(typeCache[t], &tv)
+		case TryStepType:
+			tv, err := GetTryStep(p)
+			if err != nil {
+				return berror.WithPath(err, path)
 			}
-			switch t {
-			case PutStepType:
-				fallthrough
-			case GetStepType:
-				fallthrough
-			case TaskStepType:
-				name, err := GetStepName(p)
-				berror.CheckError(err)
-				j.stepCache[t][name] = &p
-				j.typeCache[t] = append(j.typeCache[t], &p)
-			case DoStepType:
-				tv, err := GetDoStep(p)
-				berror.CheckError(err)
-				j.typeCache[t] = append(j.typeCache[t], &tv)
-				for _, as := range tv.Do {
-					n, err := GetStepName(as)
-					berror.CheckError(err)
-					j.stepCache[t][n] = &as
-				}
-			case TryStepType:
-				tv, err := GetTryStep(p)
-				berror.CheckError(err)
-				j.typeCache[t] = append(j.typeCache[t], &tv)
-				for _, as := range tv.Try {
-					n, err := GetStepName(as)
-					berror.CheckError(err)
-					j.stepCache[t][n] = &as
-				}
-			case AggregateStepType:
-				tv, err := GetAggregateStep(p)
-				berror.CheckError(err)
-				j.typeCache[t] = append(j.typeCache[t], &tv)
-				for _, as := range tv.Aggregate {
-					n, err := GetStepName(as)
-					berror.CheckError(err)
-					j.stepCache[t][n] = &as
-				}
-			default:
-				log.Fatalf("unsupported type %+v", t)
+			typeCache[t] = append(typeCache[t], &tv)
+		case AggregateStepType:
+			tv, err := GetAggregateStep(p)
+			if err != nil {
+				return berror.WithPath(err, path)
+			}
+			typeCache[t] = append(typeCache[t], &tv)
+		case InParallelStepType:
+			tv, err := GetInParallelStep(p)
+			if err != nil {
+				return berror.WithPath(err, path)
+			}
+			typeCache[t] = append(typeCache[t], &tv)
+		default:
+			return berror.Errorf(path, "unsupported step type %s", t)
+		}
+		err = indexNested(stepCache[t], path, p)
+		if err != nil {
+			return err
+		}
+	}
+	j.typeCache = typeCache
+	j.stepCache = stepCache
+	return nil
+}
+
+// indexNested indexes by name in cache the named steps the composite step s
+// holds, descending into the composite steps it holds.
+func indexNested(cache map[string]interface{}, path string, s interface{}) error {
+	key, children, err := Children(s)
+	if err != nil {
+		return berror.WithPath(err, path)
+	}
+	for k, c := range children {
+		cpath := fmt.Sprintf("%s.%s[%d]", path, key, k)
+		t, err := TypeOf(c)
+		if err != nil {
+			return berror.WithPath(err, cpath)
+		}
+		if t.IsComposite() {
+			err = indexNested(cache, cpath, c)
+			if err != nil {
+				return err
 			}
+			continue
+		}
+		n, err := GetStepName(c)
+		if err != nil {
+			return berror.WithPath(err, cpath)
 		}
+		step := c
+		cache[n] = &step
 	}
+	return nil
+}
+
+// ResetCache drops the step index, it is rebuilt from the plan on next access.
+// Call it after editing the plan directly.
+func (j *Job) ResetCache() {
+	j.stepCache = nil
+	j.typeCache = nil
 }
 
 func (j *Job) AddStepByTypeAndName(t Type, name string, i interface{}) {
@@ -185,8 +235,17 @@ func (j *Job) GetStepByTypeAndName(t Type, name string) interface{} {
 }
 
 func GetJobsFromString(data string) Jobs {
-	j := Jobs{}
-	err := yaml.Unmarshal([]byte(data), &j)
+	j, err := ParseJobs(data)
 	berror.CheckError(err)
 	return j
 }
+
+// ParseJobs is the error returning counterpart of GetJobsFromString.
+func ParseJobs(data string) (Jobs, error) {
+	j := Jobs{}
+	err := yaml.Unmarshal([]byte(data), &j)
+	if err != nil {
+		return j, berror.FromYAML(err)
+	}
+	return j, nil
+}
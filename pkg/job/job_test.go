@@ -0,0 +1,82 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildCache(t *testing.T) {
+	j := parseJob(t, `
+name: build
+plan:
+- in_parallel:
+  - do:
+    - get: repo
+    - try: {put: img}
+  - get: tools
+- task: unit
+`)
+	err := j.BuildCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		t    Type
+		name string
+	}{
+		{InParallelStepType, "repo"},
+		{InParallelStepType, "img"},
+		{InParallelStepType, "tools"},
+		{TaskStepType, "unit"},
+	}
+	for _, tt := range tests {
+		if j.GetStepByTypeAndName(tt.t, tt.name) == nil {
+			t.Errorf("%s step %s is not indexed", tt.t, tt.name)
+		}
+	}
+	if n := len(j.GetStepsByType(InParallelStepType)); n != 1 {
+		t.Errorf("got %d in_parallel steps, want 1", n)
+	}
+}
+
+func TestBuildCacheUnnamedStep(t *testing.T) {
+	j := parseJob(t, `
+name: build
+plan:
+- do:
+  - in_parallel:
+    - unknown: step
+`)
+	err := j.BuildCache()
+	if err == nil {
+		t.Fatal("want an error for the unrecognized step")
+	}
+	want := "jobs[build].plan[0].do[0].in_parallel[0]"
+	if got := err.Error(); !strings.HasPrefix(got, want) {
+		t.Errorf("got %q, want an error located at %s", got, want)
+	}
+}
+
+func TestStepErrorLocation(t *testing.T) {
+	j := parseJob(t, `
+name: build
+plan:
+- get: repo
+- do:
+    put: img
+`)
+	err := j.Walk(func(path string, st Type, s interface{}) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatal("want an error for the do step holding a map")
+	}
+	if got := err.Error(); !strings.HasPrefix(got, "plan[1]: ") || strings.Contains(got, "line") {
+		t.Errorf("got %q, want an error located at plan[1], without the line of the re-marshalled step", got)
+	}
+}
@@ -0,0 +1,321 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+var hookKeys = []string{"on_success", "on_failure", "on_error", "on_abort", "ensure"}
+
+func (j *Jobs) UpdateWith(n Jobs) Jobs {
+	res, err := j.Merge(n)
+	berror.CheckError(err)
+	return res
+}
+
+// Merge is the error returning counterpart of UpdateWith. Jobs are matched by
+// name, the ones only defined in n are appended.
+func (j *Jobs) Merge(n Jobs) (Jobs, error) {
+	nm := make(map[string]Job)
+	for _, nj := range n.Jobs {
+		nm[nj.Name] = nj
+	}
+	seen := make(map[string]bool)
+	res := Jobs{}
+	for _, jj := range j.Jobs {
+		seen[jj.Name] = true
+		if nj, ok := nm[jj.Name]; ok {
+			m, err := jj.Merge(nj)
+			if err != nil {
+				return Jobs{}, berror.WithPath(err, fmt.Sprintf("jobs[%s]", jj.Name))
+			}
+			res.Jobs = append(res.Jobs, m)
+		} else {
+			res.Jobs = append(res.Jobs, jj)
+		}
+	}
+	for _, nj := range n.Jobs {
+		if !seen[nj.Name] {
+			seen[nj.Name] = true
+			res.Jobs = append(res.Jobs, nj)
+		}
+	}
+	return res, nil
+}
+
+func (j *Job) UpdateWith(n Job) Job {
+	res, err := j.Merge(n)
+	berror.CheckError(err)
+	return res
+}
+
+// Merge is the error returning counterpart of UpdateWith. Fields set in n
+// override the ones of j. Plan steps are matched by type and name, as
+// GetStepByTypeAndName does: matching steps are merged key by key, composite
+// steps are matched through the named steps they hold and merged
+// recursively, and steps only defined in n are appended to the plan.
+func (j *Job) Merge(n Job) (Job, error) {
+	res := Job{JobBase: j.JobBase, StepHooks: j.StepHooks}
+	if n.Serial {
+		res.Serial = true
+	}
+	if n.BuildLogsToRetain != 0 {
+		res.BuildLogsToRetain = n.BuildLogsToRetain
+	}
+	if len(n.SerialGroups) != 0 {
+		res.SerialGroups = n.SerialGroups
+	}
+	if n.MaxInFlight != 0 {
+		res.MaxInFlight = n.MaxInFlight
+	}
+	if n.Public {
+		res.Public = true
+	}
+	if n.DisableManualTrigger {
+		res.DisableManualTrigger = true
+	}
+	if n.Interruptible {
+		res.Interruptible = true
+	}
+	var err error
+	res.OnSuccess, err = mergeHook(j.OnSuccess, n.OnSuccess)
+	if err != nil {
+		return res, berror.WithPath(err, "on_success")
+	}
+	res.OnFailure, err = mergeHook(j.OnFailure, n.OnFailure)
+	if err != nil {
+		return res, berror.WithPath(err, "on_failure")
+	}
+	res.OnError, err = mergeHook(j.OnError, n.OnError)
+	if err != nil {
+		return res, berror.WithPath(err, "on_error")
+	}
+	res.OnAbort, err = mergeHook(j.OnAbort, n.OnAbort)
+	if err != nil {
+		return res, berror.WithPath(err, "on_abort")
+	}
+	res.Ensure, err = mergeHook(j.Ensure, n.Ensure)
+	if err != nil {
+		return res, berror.WithPath(err, "ensure")
+	}
+	res.Plan, err = mergePlan(j.Plan, n.Plan)
+	if err != nil {
+		return res, berror.WithPath(err, "plan")
+	}
+	return res, nil
+}
+
+func mergePlan(base, overlay []interface{}) ([]interface{}, error) {
+	res := make([]interface{}, len(base))
+	copy(res, base)
+	for i, o := range overlay {
+		found := false
+		for k, b := range res {
+			same, err := sameStep(b, o)
+			if err != nil {
+				return res, berror.WithPath(err, fmt.Sprintf("[%d]", i))
+			}
+			if same {
+				res[k], err = mergeStep(b, o)
+				if err != nil {
+					return res, berror.WithPath(err, fmt.Sprintf("[%d]", i))
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, o)
+		}
+	}
+	return res, nil
+}
+
+// sameStep tells whether a and b designate the same step: named steps need
+// the same type and name, composite steps the same type and one named step in
+// common.
+func sameStep(a, b interface{}) (bool, error) {
+	at, err := TypeOf(a)
+	if err != nil {
+		return false, err
+	}
+	bt, err := TypeOf(b)
+	if err != nil {
+		return false, err
+	}
+	if at != bt {
+		return false, nil
+	}
+	an, aerr := GetStepName(a)
+	bn, berr := GetStepName(b)
+	if aerr == nil && berr == nil {
+		return an == bn, nil
+	}
+	am, err := GetStepMap(a)
+	if err != nil {
+		return false, err
+	}
+	bm, err := GetStepMap(b)
+	if err != nil {
+		return false, err
+	}
+	achildren := nestedSteps(at, am[at.String()])
+	bchildren := nestedSteps(bt, bm[bt.String()])
+	for _, ac := range achildren {
+		for _, bc := range bchildren {
+			same, err := sameStep(ac, bc)
+			if err != nil {
+				return false, err
+			}
+			if same {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func mergeStep(base, overlay interface{}) (interface{}, error) {
+	t, err := TypeOf(base)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := GetStepMap(base)
+	if err != nil {
+		return nil, err
+	}
+	om, err := GetStepMap(overlay)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[interface{}]interface{})
+	for k, v := range bm {
+		res[k] = v
+	}
+	for k, v := range om {
+		key, _ := k.(string)
+		switch {
+		case isHookKey(key):
+			res[k], err = mergeHook(res[k], v)
+			if err != nil {
+				return nil, berror.WithPath(err, key)
+			}
+		case t.IsComposite() && key == t.String():
+			steps, err := mergePlan(nestedSteps(t, res[k]), nestedSteps(t, v))
+			if err != nil {
+				return nil, berror.WithPath(err, key)
+			}
+			merged := res[k]
+			// options of the map form of in_parallel
+			if _, ok := v.(map[interface{}]interface{}); ok && t == InParallelStepType {
+				merged = types.MergeValues(res[k], v)
+			}
+			res[k] = withNestedSteps(t, merged, steps)
+		default:
+			res[k] = types.MergeValues(res[k], v)
+		}
+	}
+	return res, nil
+}
+
+// mergeHook merges hook steps designating the same step, otherwise the hook
+// of overlay replaces the one of base.
+func mergeHook(base, overlay interface{}) (interface{}, error) {
+	if overlay == nil {
+		return base, nil
+	}
+	if base == nil {
+		return overlay, nil
+	}
+	same, err := sameStep(base, overlay)
+	if err != nil {
+		return nil, err
+	}
+	if !same {
+		return overlay, nil
+	}
+	return mergeStep(base, overlay)
+}
+
+// nestedSteps returns the steps held under the key of a composite step of
+// type t: in_parallel accepts both a list of steps and a map with a steps key,
+// try holds a single step, or a list in its legacy form.
+func nestedSteps(t Type, v interface{}) []interface{} {
+	switch vv := v.(type) {
+	case []interface{}:
+		return vv
+	case map[interface{}]interface{}:
+		if t == TryStepType {
+			return []interface{}{vv}
+		}
+		steps, _ := vv["steps"].([]interface{})
+		return steps
+	default:
+		return nil
+	}
+}
+
+// withNestedSteps writes steps back under the key of a composite step of type
+// t, v being the value held so far, whose form is kept: the map form of
+// in_parallel holds its steps under a steps key, the map form of try holds a
+// single step, several being wrapped in a do step.
+func withNestedSteps(t Type, v interface{}, steps []interface{}) interface{} {
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return steps
+	}
+	switch t {
+	case TryStepType:
+		if len(steps) == 1 {
+			return steps[0]
+		}
+		return map[interface{}]interface{}{DoStepType.String(): steps}
+	case InParallelStepType:
+		m["steps"] = steps
+		return m
+	default:
+		return steps
+	}
+}
+
+func isHookKey(k string) bool {
+	for _, h := range hookKeys {
+		if h == k {
+			return true
+		}
+	}
+	return false
+}
+
+// TypeOf returns the type of step s, whatever its representation.
+func TypeOf(s interface{}) (Type, error) {
+	d, err := yaml.Marshal(&s)
+	if err != nil {
+		return UnrecognizedType, err
+	}
+	return GetType(string(d))
+}
+
+// GetStepMap returns step s as a map, whatever its representation, keeping
+// the fields typed steps do not know about.
+func GetStepMap(s interface{}) (map[interface{}]interface{}, error) {
+	res := make(map[interface{}]interface{})
+	d, err := yaml.Marshal(&s)
+	if err != nil {
+		return res, err
+	}
+	err = yaml.Unmarshal(d, &res)
+	if err != nil {
+		return res, berror.FromYAMLFragment(err)
+	}
+	return res, nil
+}
@@ -0,0 +1,79 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"testing"
+)
+
+func TestMergePlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		overlay string
+		want    string
+	}{
+		{
+			name:    "named steps merge key by key",
+			base:    `[{get: repo, trigger: true}, {task: unit, file: repo/unit.yml}]`,
+			overlay: `[{task: unit, privileged: true}]`,
+			want:    `[{get: repo, trigger: true}, {task: unit, file: repo/unit.yml, privileged: true}]`,
+		},
+		{
+			name:    "new steps are appended",
+			base:    `[{get: repo}]`,
+			overlay: `[{put: img}]`,
+			want:    `[{get: repo}, {put: img}]`,
+		},
+		{
+			name:    "steps of another type do not match",
+			base:    `[{get: repo}]`,
+			overlay: `[{put: repo}]`,
+			want:    `[{get: repo}, {put: repo}]`,
+		},
+		{
+			name:    "composite steps match through a named step",
+			base:    `[{in_parallel: [{get: repo}, {get: img}]}]`,
+			overlay: `[{in_parallel: [{get: img, trigger: true}, {get: tools}]}]`,
+			want:    `[{in_parallel: [{get: repo}, {get: img, trigger: true}, {get: tools}]}]`,
+		},
+		{
+			name:    "map form of in_parallel keeps and merges its options",
+			base:    `[{in_parallel: {limit: 1, steps: [{get: repo}]}}]`,
+			overlay: `[{in_parallel: {fail_fast: true, steps: [{get: repo, trigger: true}]}}]`,
+			want:    `[{in_parallel: {limit: 1, fail_fast: true, steps: [{get: repo, trigger: true}]}}]`,
+		},
+		{
+			name:    "single-step try merges into its step",
+			base:    `[{get: repo}, {try: {put: img, params: {image: repo/img.tar}}}]`,
+			overlay: `[{try: {put: img, get_params: {skip_download: true}}}]`,
+			want:    `[{get: repo}, {try: {put: img, params: {image: repo/img.tar}, get_params: {skip_download: true}}}]`,
+		},
+		{
+			name:    "try of another step is appended",
+			base:    `[{try: {put: img}}]`,
+			overlay: `[{try: {put: slack}}]`,
+			want:    `[{try: {put: img}}, {try: {put: slack}}]`,
+		},
+		{
+			name:    "hooks of matching steps merge",
+			base:    `[{task: unit, on_failure: {put: slack, params: {text: ko}}}]`,
+			overlay: `[{task: unit, on_failure: {put: slack, params: {channel: ci}}}]`,
+			want:    `[{task: unit, on_failure: {put: slack, params: {text: ko, channel: ci}}}]`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, _ := yamlValue(t, tt.base).([]interface{})
+			overlay, _ := yamlValue(t, tt.overlay).([]interface{})
+			got, err := mergePlan(base, overlay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertYAMLEqual(t, got, yamlValue(t, tt.want))
+		})
+	}
+}
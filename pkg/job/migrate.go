@@ -0,0 +1,93 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"fmt"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+)
+
+// MigrateAggregate rewrites s, and every step it holds, replacing deprecated
+// aggregate steps with in_parallel steps. Hooks and modifiers are kept.
+func MigrateAggregate(s interface{}) (interface{}, error) {
+	t, err := TypeOf(s)
+	if err != nil {
+		return s, err
+	}
+	m, err := GetStepMap(s)
+	if err != nil {
+		return s, err
+	}
+	for _, h := range hookKeys {
+		if m[h] == nil {
+			continue
+		}
+		m[h], err = MigrateAggregate(m[h])
+		if err != nil {
+			return s, berror.WithPath(err, h)
+		}
+	}
+	if !t.IsComposite() {
+		return m, nil
+	}
+	key := t.String()
+	steps, err := migrateAggregates(nestedSteps(t, m[key]))
+	if err != nil {
+		return s, berror.WithPath(err, key)
+	}
+	if t == AggregateStepType {
+		delete(m, key)
+		t = InParallelStepType
+		key = t.String()
+	}
+	m[key] = withNestedSteps(t, m[key], steps)
+	return m, nil
+}
+
+func migrateAggregates(steps []interface{}) ([]interface{}, error) {
+	var res []interface{}
+	for i, s := range steps {
+		ms, err := MigrateAggregate(s)
+		if err != nil {
+			return res, berror.WithPath(err, fmt.Sprintf("[%d]", i))
+		}
+		res = append(res, ms)
+	}
+	return res, nil
+}
+
+// MigrateAggregates replaces every aggregate step of the job, hooks included,
+// with an in_parallel step.
+func (j *Job) MigrateAggregates() error {
+	plan, err := migrateAggregates(j.Plan)
+	if err != nil {
+		return berror.WithPath(err, "plan")
+	}
+	hooks := []*interface{}{&j.OnSuccess, &j.OnFailure, &j.OnError, &j.OnAbort, &j.Ensure}
+	for i, h := range hooks {
+		if *h == nil {
+			continue
+		}
+		*h, err = MigrateAggregate(*h)
+		if err != nil {
+			return berror.WithPath(err, hookKeys[i])
+		}
+	}
+	j.Plan = plan
+	j.ResetCache()
+	return nil
+}
+
+func (j *Jobs) MigrateAggregates() error {
+	for i := range j.Jobs {
+		err := j.Jobs[i].MigrateAggregates()
+		if err != nil {
+			return berror.WithPath(err, fmt.Sprintf("jobs[%s]", j.Jobs[i].Name))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMigrateAggregate(t *testing.T) {
+	tests := []struct {
+		name string
+		step string
+		want string
+	}{
+		{
+			name: "named step is kept",
+			step: `{get: repo, trigger: true}`,
+			want: `{get: repo, trigger: true}`,
+		},
+		{
+			name: "aggregate becomes in_parallel",
+			step: `{aggregate: [{get: repo}, {get: img}], timeout: 1h}`,
+			want: `{in_parallel: [{get: repo}, {get: img}], timeout: 1h}`,
+		},
+		{
+			name: "nested aggregates and hooks",
+			step: `
+do:
+- aggregate:
+  - get: repo
+on_failure:
+  aggregate:
+  - put: slack
+`,
+			want: `
+do:
+- in_parallel:
+  - get: repo
+on_failure:
+  in_parallel:
+  - put: slack
+`,
+		},
+		{
+			name: "map form of in_parallel keeps its options",
+			step: `{in_parallel: {limit: 2, steps: [{aggregate: [{get: repo}]}]}}`,
+			want: `{in_parallel: {limit: 2, steps: [{in_parallel: [{get: repo}]}]}}`,
+		},
+		{
+			name: "single step of try is left alone",
+			step: `{try: {put: img, params: {image: repo/img.tar}}}`,
+			want: `{try: {put: img, params: {image: repo/img.tar}}}`,
+		},
+		{
+			name: "single step of try is migrated",
+			step: `{try: {aggregate: [{put: img}]}}`,
+			want: `{try: {in_parallel: [{put: img}]}}`,
+		},
+		{
+			name: "legacy list form of try",
+			step: `{try: [{aggregate: [{put: img}]}]}`,
+			want: `{try: [{in_parallel: [{put: img}]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MigrateAggregate(yamlValue(t, tt.step))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertYAMLEqual(t, got, yamlValue(t, tt.want))
+		})
+	}
+}
+
+// yamlValue parses data into its generic representation.
+func yamlValue(t *testing.T, data string) interface{} {
+	t.Helper()
+	var res interface{}
+	err := yaml.Unmarshal([]byte(data), &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// assertYAMLEqual compares got and want through their yaml representation,
+// typed steps and maps comparing alike.
+func assertYAMLEqual(t *testing.T, got, want interface{}) {
+	t.Helper()
+	g, err := yaml.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := yaml.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(yamlValue(t, string(g)), yamlValue(t, string(w))) {
+		t.Errorf("got:\n%s\nwant:\n%s", g, w)
+	}
+}
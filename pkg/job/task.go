@@ -23,11 +23,59 @@ const (
 	AggregateStepType
 	DoStepType
 	TryStepType
+	InParallelStepType
+	SetPipelineStepType
+	LoadVarStepType
 	UnrecognizedType
 
 	TypeNotSupportedError types.InternalError = "specified type is not supported"
 )
 
+func (t Type) String() string {
+	switch t {
+	case GetStepType:
+		return "get"
+	case PutStepType:
+		return "put"
+	case TaskStepType:
+		return "task"
+	case AggregateStepType:
+		return "aggregate"
+	case DoStepType:
+		return "do"
+	case TryStepType:
+		return "try"
+	case InParallelStepType:
+		return "in_parallel"
+	case SetPipelineStepType:
+		return "set_pipeline"
+	case LoadVarStepType:
+		return "load_var"
+	default:
+		return "unrecognized"
+	}
+}
+
+// TypeFromString returns the step type named s, e.g. "put".
+func TypeFromString(s string) (Type, error) {
+	for t := GetStepType; t < UnrecognizedType; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return UnrecognizedType, TypeNotSupportedError
+}
+
+// IsComposite tells whether steps of type t hold a list of other steps.
+func (t Type) IsComposite() bool {
+	switch t {
+	case AggregateStepType, DoStepType, TryStepType, InParallelStepType:
+		return true
+	default:
+		return false
+	}
+}
+
 type Step struct {
 	StepHooks     `yaml:",inline"`
 	StepModifiers `yaml:",inline"`
@@ -36,14 +84,27 @@ type Step struct {
 type StepHooks struct {
 	OnSuccess interface{} `yaml:"on_success,omitempty"`
 	OnFailure interface{} `yaml:"on_failure,omitempty"`
+	OnError   interface{} `yaml:"on_error,omitempty"`
 	OnAbort   interface{} `yaml:"on_abort,omitempty"`
 	Ensure    interface{} `yaml:"ensure,omitempty"`
 }
 
 type StepModifiers struct {
-	Tags     []string `yaml:"tags,omitempty"`
-	Timeout  string   `yaml:"timeout,omitempty"`
-	Attempts string   `yaml:"attempts,omitempty"`
+	Tags     []string    `yaml:"tags,omitempty"`
+	Timeout  string      `yaml:"timeout,omitempty"`
+	Attempts string      `yaml:"attempts,omitempty"`
+	Across   []AcrossVar `yaml:"across,omitempty"`
+}
+
+// AcrossVar runs the step once for every value of Var.
+type AcrossVar struct {
+	Var string `yaml:"var"`
+	// a list of values or a ((var)) holding them
+	Values interface{} `yaml:"values"`
+	// optional fields
+	// a number or "all"
+	MaxInFlight interface{} `yaml:"max_in_flight,omitempty"`
+	FailFast    bool        `yaml:"fail_fast,omitempty"`
 }
 
 type GetStep struct {
@@ -57,6 +118,14 @@ type GetStep struct {
 	Trigger  bool        `yaml:"trigger,omitempty"`
 }
 
+// ResourceName returns the name of the resource fetched by the step.
+func (s *GetStep) ResourceName() string {
+	if s.Resource != "" {
+		return s.Resource
+	}
+	return s.Get
+}
+
 func (s *GetStep) String() string {
 	b, err := yaml.Marshal(*s)
 	berror.CheckError(err)
@@ -72,11 +141,19 @@ type PutStep struct {
 	GetParams interface{} `yaml:"get_params,omitempty"`
 }
 
+// ResourceName returns the name of the resource updated by the step.
+func (s *PutStep) ResourceName() string {
+	if s.Resource != "" {
+		return s.Resource
+	}
+	return s.Put
+}
+
 type TaskStep struct {
 	Step `yaml:",inline"`
 	Task string `yaml:"task"`
 	// optional fields
-	Config        interface{}                 `yaml:"config,omitempty"`
+	Config        *TaskConfig                 `yaml:"config,omitempty"`
 	File          string                      `yaml:"file,omitempty"`
 	Privileged    bool                        `yaml:"privileged,omitempty"`
 	Params        map[interface{}]interface{} `yaml:"params,omitempty"`
@@ -101,16 +178,98 @@ type DoStep struct {
 	Do   []interface{} `yaml:"do"`
 }
 
+// TryStep runs a single step, ignoring its failure. The list form written by
+// older pipelines is still read, Concourse only accepts a step.
 type TryStep struct {
 	Step `yaml:",inline"`
-	Try  []interface{} `yaml:"try"`
+	Try  interface{} `yaml:"try"`
+}
+
+// Steps returns the step tried, or the steps of the legacy list form.
+func (s *TryStep) Steps() []interface{} {
+	switch v := s.Try.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+type InParallelStep struct {
+	Step       `yaml:",inline"`
+	InParallel InParallelConfig `yaml:"in_parallel"`
+}
+
+func (s *InParallelStep) String() string {
+	b, err := yaml.Marshal(*s)
+	berror.CheckError(err)
+	return string(b[:])
+}
+
+// InParallelConfig is either written as a plain list of steps, or as a map
+// when limit or fail_fast are set.
+type InParallelConfig struct {
+	Steps []interface{} `yaml:"steps"`
+	// optional fields
+	Limit    int  `yaml:"limit,omitempty"`
+	FailFast bool `yaml:"fail_fast,omitempty"`
+}
+
+type inParallelConfig InParallelConfig
+
+func (c *InParallelConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var steps []interface{}
+	err := unmarshal(&steps)
+	if err == nil {
+		*c = InParallelConfig{Steps: steps}
+		return nil
+	}
+	res := inParallelConfig{}
+	err = unmarshal(&res)
+	if err != nil {
+		return err
+	}
+	*c = InParallelConfig(res)
+	return nil
+}
+
+func (c InParallelConfig) MarshalYAML() (interface{}, error) {
+	if c.Limit == 0 && !c.FailFast {
+		if c.Steps == nil {
+			return []interface{}{}, nil
+		}
+		return c.Steps, nil
+	}
+	return inParallelConfig(c), nil
+}
+
+type SetPipelineStep struct {
+	Step        `yaml:",inline"`
+	SetPipeline string `yaml:"set_pipeline"`
+	File        string `yaml:"file"`
+	// optional fields
+	Vars         interface{} `yaml:"vars,omitempty"`
+	VarFiles     []string    `yaml:"var_files,omitempty"`
+	Team         string      `yaml:"team,omitempty"`
+	InstanceVars interface{} `yaml:"instance_vars,omitempty"`
+}
+
+type LoadVarStep struct {
+	Step    `yaml:",inline"`
+	LoadVar string `yaml:"load_var"`
+	File    string `yaml:"file"`
+	// optional fields
+	Format string `yaml:"format,omitempty"`
+	Reveal bool   `yaml:"reveal,omitempty"`
 }
 
 func GetType(s string) (Type, error) {
 	res := make(map[string]interface{})
 	err := yaml.Unmarshal([]byte(s), &res)
 	if err != nil {
-		return UnrecognizedType, err
+		return UnrecognizedType, berror.FromYAMLFragment(err)
 	}
 	if _, ok := res["get"]; ok {
 		return GetStepType, nil
@@ -124,6 +283,12 @@ func GetType(s string) (Type, error) {
 		return DoStepType, nil
 	} else if _, ok := res["try"]; ok {
 		return TryStepType, nil
+	} else if _, ok := res["in_parallel"]; ok {
+		return InParallelStepType, nil
+	} else if _, ok := res["set_pipeline"]; ok {
+		return SetPipelineStepType, nil
+	} else if _, ok := res["load_var"]; ok {
+		return LoadVarStepType, nil
 	} else {
 		return UnrecognizedType, nil
 	}
@@ -141,17 +306,19 @@ func GetTaskStep(s interface{}) (TaskStep, error) {
 	}
 	switch t {
 	case TaskStepType:
-		return getTaskStepFromString(string(d)), nil
+		return getTaskStepFromString(string(d))
 	default:
 		return TaskStep{}, errors.New("not a task Step")
 	}
 }
 
-func getTaskStepFromString(data string) TaskStep {
+func getTaskStepFromString(data string) (TaskStep, error) {
 	j := TaskStep{}
 	err := yaml.Unmarshal([]byte(data), &j)
-	berror.CheckError(err)
-	return j
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
 }
 
 func GetPutStep(s interface{}) (PutStep, error) {
@@ -165,17 +332,19 @@ func GetPutStep(s interface{}) (PutStep, error) {
 	}
 	switch t {
 	case PutStepType:
-		return getPutStepFromString(string(d)), nil
+		return getPutStepFromString(string(d))
 	default:
 		return PutStep{}, errors.New("not a put Step")
 	}
 }
 
-func getPutStepFromString(data string) PutStep {
+func getPutStepFromString(data string) (PutStep, error) {
 	j := PutStep{}
 	err := yaml.Unmarshal([]byte(data), &j)
-	berror.CheckError(err)
-	return j
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
 }
 
 func GetGetStep(s interface{}) (GetStep, error) {
@@ -189,17 +358,19 @@ func GetGetStep(s interface{}) (GetStep, error) {
 	}
 	switch t {
 	case GetStepType:
-		return getGetStepFromString(string(d)), nil
+		return getGetStepFromString(string(d))
 	default:
 		return GetStep{}, errors.New("not a get Step")
 	}
 }
 
-func getGetStepFromString(data string) GetStep {
+func getGetStepFromString(data string) (GetStep, error) {
 	j := GetStep{}
 	err := yaml.Unmarshal([]byte(data), &j)
-	berror.CheckError(err)
-	return j
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
 }
 
 func GetAggregateStep(s interface{}) (AggregateStep, error) {
@@ -213,17 +384,19 @@ func GetAggregateStep(s interface{}) (AggregateStep, error) {
 	}
 	switch t {
 	case AggregateStepType:
-		return getAggregateStepFromString(string(d)), nil
+		return getAggregateStepFromString(string(d))
 	default:
 		return AggregateStep{}, errors.New("not a aggregate Step")
 	}
 }
 
-func getAggregateStepFromString(data string) AggregateStep {
+func getAggregateStepFromString(data string) (AggregateStep, error) {
 	j := AggregateStep{}
 	err := yaml.Unmarshal([]byte(data), &j)
-	berror.CheckError(err)
-	return j
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
 }
 
 func GetDoStep(s interface{}) (DoStep, error) {
@@ -237,17 +410,19 @@ func GetDoStep(s interface{}) (DoStep, error) {
 	}
 	switch t {
 	case DoStepType:
-		return getDoStepFromString(string(d)), nil
+		return getDoStepFromString(string(d))
 	default:
 		return DoStep{}, errors.New("not a do Step")
 	}
 }
 
-func getDoStepFromString(data string) DoStep {
+func getDoStepFromString(data string) (DoStep, error) {
 	j := DoStep{}
 	err := yaml.Unmarshal([]byte(data), &j)
-	berror.CheckError(err)
-	return j
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
 }
 
 func GetTryStep(s interface{}) (TryStep, error) {
@@ -261,17 +436,97 @@ func GetTryStep(s interface{}) (TryStep, error) {
 	}
 	switch t {
 	case TryStepType:
-		return getTryStepFromString(string(d)), nil
+		return getTryStepFromString(string(d))
 	default:
 		return TryStep{}, errors.New("not a try Step")
 	}
 }
 
-func getTryStepFromString(data string) TryStep {
+func getTryStepFromString(data string) (TryStep, error) {
 	j := TryStep{}
 	err := yaml.Unmarshal([]byte(data), &j)
-	berror.CheckError(err)
-	return j
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
+}
+
+func GetInParallelStep(s interface{}) (InParallelStep, error) {
+	d, err := yaml.Marshal(&s)
+	if err != nil {
+		return InParallelStep{}, err
+	}
+	t, err := GetType(string(d))
+	if err != nil {
+		return InParallelStep{}, err
+	}
+	switch t {
+	case InParallelStepType:
+		return getInParallelStepFromString(string(d))
+	default:
+		return InParallelStep{}, errors.New("not a in_parallel Step")
+	}
+}
+
+func getInParallelStepFromString(data string) (InParallelStep, error) {
+	j := InParallelStep{}
+	err := yaml.Unmarshal([]byte(data), &j)
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
+}
+
+func GetSetPipelineStep(s interface{}) (SetPipelineStep, error) {
+	d, err := yaml.Marshal(&s)
+	if err != nil {
+		return SetPipelineStep{}, err
+	}
+	t, err := GetType(string(d))
+	if err != nil {
+		return SetPipelineStep{}, err
+	}
+	switch t {
+	case SetPipelineStepType:
+		return getSetPipelineStepFromString(string(d))
+	default:
+		return SetPipelineStep{}, errors.New("not a set_pipeline Step")
+	}
+}
+
+func getSetPipelineStepFromString(data string) (SetPipelineStep, error) {
+	j := SetPipelineStep{}
+	err := yaml.Unmarshal([]byte(data), &j)
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
+}
+
+func GetLoadVarStep(s interface{}) (LoadVarStep, error) {
+	d, err := yaml.Marshal(&s)
+	if err != nil {
+		return LoadVarStep{}, err
+	}
+	t, err := GetType(string(d))
+	if err != nil {
+		return LoadVarStep{}, err
+	}
+	switch t {
+	case LoadVarStepType:
+		return getLoadVarStepFromString(string(d))
+	default:
+		return LoadVarStep{}, errors.New("not a load_var Step")
+	}
+}
+
+func getLoadVarStepFromString(data string) (LoadVarStep, error) {
+	j := LoadVarStep{}
+	err := yaml.Unmarshal([]byte(data), &j)
+	if err != nil {
+		return j, berror.FromYAMLFragment(err)
+	}
+	return j, nil
 }
 
 func GetStepName(i interface{}) (string, error) {
@@ -286,16 +541,19 @@ func GetStepName(i interface{}) (string, error) {
 	switch t {
 	case PutStepType:
 		tv, err := GetPutStep(i)
-		berror.CheckError(err)
-		return tv.Put, nil
+		return tv.Put, err
 	case GetStepType:
 		tv, err := GetGetStep(i)
-		berror.CheckError(err)
-		return tv.Get, nil
+		return tv.Get, err
 	case TaskStepType:
 		tv, err := GetTaskStep(i)
-		berror.CheckError(err)
-		return tv.Task, nil
+		return tv.Task, err
+	case SetPipelineStepType:
+		tv, err := GetSetPipelineStep(i)
+		return tv.SetPipeline, err
+	case LoadVarStepType:
+		tv, err := GetLoadVarStep(i)
+		return tv.LoadVar, err
 	default:
 		return "", TypeNotSupportedError
 	}
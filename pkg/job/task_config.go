@@ -0,0 +1,194 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+)
+
+// TaskConfig is the configuration of a task, written inline under the config
+// of a task step or in the file the step points to.
+type TaskConfig struct {
+	Platform string        `yaml:"platform"`
+	Run      TaskRunConfig `yaml:"run"`
+	// optional fields
+	ImageResource   *TaskImageResource          `yaml:"image_resource,omitempty"`
+	RootfsURI       string                      `yaml:"rootfs_uri,omitempty"`
+	Inputs          []TaskInput                 `yaml:"inputs,omitempty"`
+	Outputs         []TaskOutput                `yaml:"outputs,omitempty"`
+	Caches          []TaskCache                 `yaml:"caches,omitempty"`
+	Params          map[interface{}]interface{} `yaml:"params,omitempty"`
+	ContainerLimits *TaskContainerLimits        `yaml:"container_limits,omitempty"`
+}
+
+type TaskImageResource struct {
+	Type   string      `yaml:"type"`
+	Source interface{} `yaml:"source"`
+	// optional fields
+	Params  interface{} `yaml:"params,omitempty"`
+	Version interface{} `yaml:"version,omitempty"`
+}
+
+type TaskInput struct {
+	Name string `yaml:"name"`
+	// optional fields
+	Path     string `yaml:"path,omitempty"`
+	Optional bool   `yaml:"optional,omitempty"`
+}
+
+type TaskOutput struct {
+	Name string `yaml:"name"`
+	// optional fields
+	Path string `yaml:"path,omitempty"`
+}
+
+type TaskCache struct {
+	Path string `yaml:"path"`
+}
+
+type TaskRunConfig struct {
+	Path string `yaml:"path"`
+	// optional fields
+	Args []string `yaml:"args,omitempty"`
+	Dir  string   `yaml:"dir,omitempty"`
+	User string   `yaml:"user,omitempty"`
+}
+
+type TaskContainerLimits struct {
+	CPU    interface{} `yaml:"cpu,omitempty"`
+	Memory interface{} `yaml:"memory,omitempty"`
+}
+
+func (c *TaskConfig) String() string {
+	b, err := yaml.Marshal(*c)
+	berror.CheckError(err)
+	return string(b[:])
+}
+
+// Input returns the input of the task named name.
+func (c *TaskConfig) Input(name string) (TaskInput, bool) {
+	for _, i := range c.Inputs {
+		if i.Name == name {
+			return i, true
+		}
+	}
+	return TaskInput{}, false
+}
+
+// Output returns the output of the task named name.
+func (c *TaskConfig) Output(name string) (TaskOutput, bool) {
+	for _, o := range c.Outputs {
+		if o.Name == name {
+			return o, true
+		}
+	}
+	return TaskOutput{}, false
+}
+
+// Validate reports the fields Concourse requires that c lacks.
+func (c *TaskConfig) Validate() error {
+	if c.Platform == "" {
+		return berror.Errorf("platform", "missing platform")
+	}
+	if c.Run.Path == "" {
+		return berror.Errorf("run.path", "missing path of the command to run")
+	}
+	return nil
+}
+
+func GetTaskConfigFromString(data string) TaskConfig {
+	c, err := ParseTaskConfig(data)
+	berror.CheckError(err)
+	return c
+}
+
+// ParseTaskConfig is the error returning counterpart of
+// GetTaskConfigFromString.
+func ParseTaskConfig(data string) (TaskConfig, error) {
+	c := TaskConfig{}
+	err := yaml.Unmarshal([]byte(data), &c)
+	if err != nil {
+		return c, berror.FromYAML(err)
+	}
+	return c, nil
+}
+
+// LoadTaskConfig reads the task config defined in filename.
+func LoadTaskConfig(filename string) (TaskConfig, error) {
+	data, err := ioutils.LoadFile(filename)
+	if err != nil {
+		return TaskConfig{}, err
+	}
+	c, err := ParseTaskConfig(data)
+	if err != nil {
+		return c, berror.WithFile(err, filename)
+	}
+	return c, nil
+}
+
+// FileArtifact returns the artifact a step file, e.g. the file of a task,
+// is read from: the first element of its path.
+func FileArtifact(file string) string {
+	return strings.SplitN(filepath.ToSlash(file), "/", 2)[0]
+}
+
+// TaskConfigResolver loads the config of the tasks defined in a file, file
+// being the path written in the step, e.g. "repo/ci/build.yml".
+type TaskConfigResolver interface {
+	Resolve(file string) (TaskConfig, error)
+}
+
+// DirResolver resolves task files from a local directory tree: the artifact
+// of a file is looked up in Root under its name, unless Artifacts maps it to
+// another directory, e.g. "repo" to ".".
+type DirResolver struct {
+	Root      string
+	Artifacts map[string]string
+}
+
+func (r *DirResolver) Resolve(file string) (TaskConfig, error) {
+	return LoadTaskConfig(r.Path(file))
+}
+
+// Path returns the local path of file.
+func (r *DirResolver) Path(file string) string {
+	parts := strings.SplitN(filepath.ToSlash(file), "/", 2)
+	dir, ok := r.Artifacts[parts[0]]
+	if !ok {
+		dir = filepath.Join(r.Root, parts[0])
+	}
+	if len(parts) == 1 {
+		return dir
+	}
+	return filepath.Join(dir, filepath.FromSlash(parts[1]))
+}
+
+// GetConfig returns the config of the task: its inline config, or the one
+// r resolves from its file. The config is not found when the task only has
+// a file and r is nil or does not find it.
+func (s *TaskStep) GetConfig(r TaskConfigResolver) (TaskConfig, bool, error) {
+	if s.Config != nil {
+		return *s.Config, true, nil
+	}
+	if s.File == "" || r == nil {
+		return TaskConfig{}, false, nil
+	}
+	c, err := r.Resolve(s.File)
+	if os.IsNotExist(err) {
+		return c, false, nil
+	}
+	if err != nil {
+		return c, false, berror.WithPath(err, "file")
+	}
+	return c, true, nil
+}
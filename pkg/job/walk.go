@@ -0,0 +1,119 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+)
+
+// WalkFunc is called for every step visited by Walk. path locates the step
+// inside the job, e.g. "plan[0].aggregate[1]" or "plan[2].on_failure".
+type WalkFunc func(path string, t Type, step interface{}) error
+
+// Walk visits every step of the job plan in order, descending into composite
+// steps and step hooks. Job level hooks are visited last.
+func (j *Job) Walk(fn WalkFunc) error {
+	err := walkSteps("plan", j.Plan, fn)
+	if err != nil {
+		return err
+	}
+	return walkHooks("", j.StepHooks, fn)
+}
+
+func walkSteps(prefix string, steps []interface{}, fn WalkFunc) error {
+	for i, s := range steps {
+		err := walkStep(fmt.Sprintf("%s[%d]", prefix, i), s, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkStep(path string, s interface{}, fn WalkFunc) error {
+	d, err := yaml.Marshal(&s)
+	if err != nil {
+		return berror.WithPath(err, path)
+	}
+	t, err := GetType(string(d))
+	if err != nil {
+		return berror.WithPath(err, path)
+	}
+	err = fn(path, t, s)
+	if err != nil {
+		return err
+	}
+	key, children, err := Children(s)
+	if err != nil {
+		return berror.WithPath(err, path)
+	}
+	err = walkSteps(path+"."+key, children, fn)
+	if err != nil {
+		return err
+	}
+	st := Step{}
+	err = yaml.Unmarshal(d, &st)
+	if err != nil {
+		return berror.WithPath(berror.FromYAMLFragment(err), path)
+	}
+	return walkHooks(path, st.StepHooks, fn)
+}
+
+// Children returns the key under which the composite step s holds its steps,
+// along with these steps. A try step holds a single child. Other steps have
+// none.
+func Children(s interface{}) (string, []interface{}, error) {
+	t, err := TypeOf(s)
+	if err != nil {
+		return "", nil, err
+	}
+	switch t {
+	case AggregateStepType:
+		tv, err := GetAggregateStep(s)
+		return t.String(), tv.Aggregate, err
+	case DoStepType:
+		tv, err := GetDoStep(s)
+		return t.String(), tv.Do, err
+	case TryStepType:
+		tv, err := GetTryStep(s)
+		return t.String(), tv.Steps(), err
+	case InParallelStepType:
+		tv, err := GetInParallelStep(s)
+		return t.String(), tv.InParallel.Steps, err
+	}
+	return "", nil, nil
+}
+
+func walkHooks(path string, h StepHooks, fn WalkFunc) error {
+	hooks := []struct {
+		key  string
+		step interface{}
+	}{
+		{"on_success", h.OnSuccess},
+		{"on_failure", h.OnFailure},
+		{"on_error", h.OnError},
+		{"on_abort", h.OnAbort},
+		{"ensure", h.Ensure},
+	}
+	for _, hook := range hooks {
+		if hook.step == nil {
+			continue
+		}
+		p := hook.key
+		if path != "" {
+			p = path + "." + hook.key
+		}
+		err := walkStep(p, hook.step, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
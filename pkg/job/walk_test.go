@@ -0,0 +1,113 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package job
+
+import (
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestWalk(t *testing.T) {
+	tests := []struct {
+		name string
+		job  string
+		want []string
+	}{
+		{
+			name: "try holds a single step",
+			job: `
+name: build
+plan:
+- get: repo
+- try:
+    put: img
+    params: {build: repo}
+`,
+			want: []string{"plan[0] get", "plan[1] try", "plan[1].try[0] put"},
+		},
+		{
+			name: "legacy list form of try",
+			job: `
+name: build
+plan:
+- try:
+  - get: repo
+  - put: img
+`,
+			want: []string{"plan[0] try", "plan[0].try[0] get", "plan[0].try[1] put"},
+		},
+		{
+			name: "composite steps and hooks",
+			job: `
+name: build
+plan:
+- in_parallel:
+    limit: 1
+    steps:
+    - do:
+      - get: repo
+      - task: unit
+        on_failure: {put: slack}
+ensure: {put: lock}
+`,
+			want: []string{
+				"plan[0] in_parallel",
+				"plan[0].in_parallel[0] do",
+				"plan[0].in_parallel[0].do[0] get",
+				"plan[0].in_parallel[0].do[1] task",
+				"plan[0].in_parallel[0].do[1].on_failure put",
+				"ensure put",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := parseJob(t, tt.job)
+			var got []string
+			err := j.Walk(func(path string, st Type, s interface{}) error {
+				got = append(got, path+" "+st.String())
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChildren(t *testing.T) {
+	j := parseJob(t, `
+name: build
+plan:
+- try: {put: img, params: {build: repo}}
+`)
+	key, children, err := Children(j.Plan[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "try" || len(children) != 1 {
+		t.Fatalf("got %s with %d children, want try with a single child", key, len(children))
+	}
+	if st, err := TypeOf(children[0]); err != nil || st != PutStepType {
+		t.Errorf("got child of type %s (%v), want put", st, err)
+	}
+}
+
+// parseJob parses the job defined in data.
+func parseJob(t *testing.T, data string) Job {
+	t.Helper()
+	j := Job{}
+	err := yaml.Unmarshal([]byte(data), &j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
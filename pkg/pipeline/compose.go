@@ -0,0 +1,166 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+)
+
+// Layout of a pipeline split into several files, every file holding a
+// fragment of pipeline, e.g. "resources:" for ResourcesFile.
+const (
+	ResourcesFile     = "resources.yml"
+	ResourceTypesFile = "resource_types.yml"
+	GroupsFile        = "groups.yml"
+	JobsDir           = "jobs"
+)
+
+var jobFileNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Compose assembles the pipeline split in dir: resources.yml,
+// resource_types.yml, groups.yml and the files of the jobs folder, all of them
+// optional. Components keep the order of their definition, job files being
+// read in lexical order. Components defined twice are reported as errors.
+func Compose(dir string) (Pipeline, error) {
+	res := Pipeline{}
+	seen := make(map[string]string)
+	define := func(kind, name, file string) error {
+		key := fmt.Sprintf("%s[%s]", kind, name)
+		if prev, ok := seen[key]; ok {
+			return berror.WithFile(berror.Errorf(key, "already defined in %s", prev), file)
+		}
+		seen[key] = file
+		return nil
+	}
+
+	files := []string{
+		filepath.Join(dir, ResourceTypesFile),
+		filepath.Join(dir, ResourcesFile),
+		filepath.Join(dir, GroupsFile),
+	}
+	jf, err := jobFiles(dir)
+	if err != nil {
+		return res, err
+	}
+	files = append(files, jf...)
+
+	for _, f := range files {
+		p, err := LoadPipeline(f)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+		for _, r := range p.ResourceTypes.ResourceTypes {
+			if err := define("resource_types", r.Name, f); err != nil {
+				return res, err
+			}
+			res.ResourceTypes.ResourceTypes = append(res.ResourceTypes.ResourceTypes, r)
+		}
+		for _, r := range p.Resources.Resources {
+			if err := define("resources", r.Name, f); err != nil {
+				return res, err
+			}
+			res.Resources.Resources = append(res.Resources.Resources, r)
+		}
+		for _, g := range p.Groups.Groups {
+			if err := define("groups", g.Name, f); err != nil {
+				return res, err
+			}
+			res.Groups.Groups = append(res.Groups.Groups, g)
+		}
+		for _, j := range p.Jobs.Jobs {
+			if err := define("jobs", j.Name, f); err != nil {
+				return res, err
+			}
+			res.Jobs.Jobs = append(res.Jobs.Jobs, j)
+		}
+	}
+	return res, nil
+}
+
+func jobFiles(dir string) ([]string, error) {
+	var res []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		m, err := filepath.Glob(filepath.Join(dir, JobsDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, m...)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// Split writes p into dir, along the layout read by Compose: every job is
+// written to a file of the jobs folder named after it. Files already present
+// are only overwritten when force is set, and job files left from a previous
+// split are removed, so that the folder composes back to p, its jobs sorted
+// by file name.
+func (p *Pipeline) Split(dir string, force bool) error {
+	files := make(map[string]string)
+	if len(p.ResourceTypes.ResourceTypes) != 0 {
+		files[filepath.Join(dir, ResourceTypesFile)] = p.ResourceTypes.String()
+	}
+	if len(p.Resources.Resources) != 0 {
+		files[filepath.Join(dir, ResourcesFile)] = p.Resources.String()
+	}
+	if len(p.Groups.Groups) != 0 {
+		files[filepath.Join(dir, GroupsFile)] = p.Groups.String()
+	}
+	for _, j := range p.Jobs.Jobs {
+		f := filepath.Join(dir, JobsDir, jobFileNameRegexp.ReplaceAllString(j.Name, "_")+".yml")
+		if _, ok := files[f]; ok {
+			return berror.Errorf(fmt.Sprintf("jobs[%s]", j.Name), "job file %s is already used by another job", f)
+		}
+		jobs := job.Jobs{Jobs: []job.Job{j}}
+		files[f] = jobs.String()
+	}
+
+	existing, err := jobFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range []string{ResourceTypesFile, ResourcesFile, GroupsFile} {
+		f = filepath.Join(dir, f)
+		if _, err := os.Stat(f); err == nil {
+			existing = append(existing, f)
+		}
+	}
+	if len(existing) != 0 && !force {
+		return fmt.Errorf("%s already holds a split pipeline, e.g. %s", dir, existing[0])
+	}
+	for _, f := range existing {
+		if _, ok := files[f]; !ok {
+			err = os.Remove(f)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = ioutils.EnsureDir(filepath.Join(dir, JobsDir))
+	if err != nil {
+		return err
+	}
+	for f, content := range files {
+		err = ioutil.WriteFile(f, []byte(content), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
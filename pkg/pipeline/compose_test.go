@@ -0,0 +1,200 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// splitPipeline lists its jobs in the lexical order of their files, the one
+// Compose reads them in.
+const splitPipeline = `
+resource_types:
+- name: slack
+  type: registry-image
+  source: {repository: example/slack}
+resources:
+- name: repo
+  type: git
+  source: {uri: "git@example.com:ci.git"}
+- name: alert
+  type: slack
+groups:
+- name: all
+  jobs: [unit, deploy/prod]
+jobs:
+- name: deploy/prod
+  plan:
+  - get: repo
+    passed: [unit]
+  on_failure: {put: alert}
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+`
+
+func TestSplitCompose(t *testing.T) {
+	p := parsePipeline(t, splitPipeline)
+	dir := t.TempDir()
+	if err := p.Split(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, dir, []string{
+		"groups.yml",
+		"jobs/deploy_prod.yml",
+		"jobs/unit.yml",
+		"resource_types.yml",
+		"resources.yml",
+	})
+	got, err := Compose(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != p.String() {
+		t.Errorf("got:\n%s\nwant:\n%s", got.String(), p.String())
+	}
+}
+
+func TestSplitOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	p := parsePipeline(t, splitPipeline)
+	if err := p.Split(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	// resource types, groups and a job are gone
+	next := parsePipeline(t, `
+resources:
+- name: repo
+  type: git
+  source: {uri: "git@example.com:ci.git"}
+jobs:
+- name: unit
+  plan:
+  - get: repo
+`)
+	err := next.Split(dir, false)
+	if err == nil || !strings.Contains(err.Error(), "already holds a split pipeline") {
+		t.Fatalf("got error %v, want a refusal to overwrite", err)
+	}
+	got, err := Compose(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != p.String() {
+		t.Errorf("refused split modified %s:\n%s", dir, got.String())
+	}
+
+	if err := next.Split(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	assertFiles(t, dir, []string{"jobs/unit.yml", "resources.yml"})
+	got, err = Compose(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != next.String() {
+		t.Errorf("got:\n%s\nwant:\n%s", got.String(), next.String())
+	}
+}
+
+func TestSplitJobFileCollision(t *testing.T) {
+	p := parsePipeline(t, `
+jobs:
+- name: deploy/prod
+  plan: []
+- name: deploy_prod
+  plan: []
+`)
+	err := p.Split(t.TempDir(), false)
+	if err == nil || !strings.Contains(err.Error(), "jobs[deploy_prod]: job file") {
+		t.Errorf("got error %v, want the job file collision", err)
+	}
+}
+
+func TestComposeDuplicates(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		err   string
+	}{
+		{
+			name: "job defined in two files",
+			files: map[string]string{
+				"jobs/a.yml": "jobs:\n- name: unit\n  plan: []\n",
+				"jobs/b.yml": "jobs:\n- name: unit\n  plan: []\n",
+			},
+			err: "jobs/b.yml: jobs[unit]: already defined in ",
+		},
+		{
+			name: "resource defined twice in a file",
+			files: map[string]string{
+				"resources.yml": "resources:\n- {name: repo, type: git}\n- {name: repo, type: git}\n",
+			},
+			err: "resources.yml: resources[repo]: already defined in ",
+		},
+		{
+			name: "resource defined along a job",
+			files: map[string]string{
+				"resources.yml": "resources:\n- {name: repo, type: git}\n",
+				"jobs/unit.yml": "resources:\n- {name: repo, type: git}\njobs:\n- name: unit\n  plan: []\n",
+			},
+			err: "jobs/unit.yml: resources[repo]: already defined in ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for f, content := range tt.files {
+				f = filepath.Join(dir, f)
+				if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(f, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			_, err := Compose(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func parsePipeline(t *testing.T, data string) Pipeline {
+	t.Helper()
+	p, err := ParsePipeline(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// assertFiles checks that dir holds the files want, given relative to it in
+// lexical order.
+func assertFiles(t *testing.T, dir string, want []string) {
+	t.Helper()
+	var got []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		got = append(got, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got files %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,241 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package pipeline
+
+import (
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/yamlnode"
+)
+
+// Canonical key order of the components of a pipeline, keys missing from
+// these lists follow in their original order.
+var (
+	sectionKeys      = []string{"display", "var_sources", "groups", "resource_types", "resources", "jobs"}
+	groupKeys        = []string{"name", "jobs", "resources"}
+	resourceTypeKeys = []string{"name", "type", "source", "privileged", "params", "check_every", "tags", "defaults"}
+	resourceKeys     = []string{"name", "old_name", "type", "icon", "source", "version", "check_every", "check_timeout", "tags", "public", "webhook_token"}
+	jobKeys          = []string{"name", "old_name", "serial", "serial_groups", "max_in_flight", "build_log_retention", "build_logs_to_retain", "public", "disable_manual_trigger", "interruptible", "plan", "on_success", "on_failure", "on_error", "on_abort", "ensure"}
+	stepKeys         = []string{
+		"get", "put", "task", "set_pipeline", "load_var", "aggregate", "do", "try", "in_parallel",
+		"resource", "passed", "trigger", "version", "inputs", "params", "get_params", "no_get",
+		"config", "file", "image", "privileged", "input_mapping", "output_mapping",
+		"vars", "var_files", "instance_vars", "team", "format", "reveal",
+		"across", "tags", "timeout", "attempts",
+		"on_success", "on_failure", "on_error", "on_abort", "ensure",
+	}
+	inParallelKeys = []string{"steps", "limit", "fail_fast"}
+	taskConfigKeys = []string{"platform", "image_resource", "rootfs_uri", "inputs", "outputs", "caches", "params", "run", "container_limits"}
+	stepHookKeys   = []string{"on_success", "on_failure", "on_error", "on_abort", "ensure"}
+)
+
+type FormatOptions struct {
+	// SortJobs orders jobs after the jobs their passed constraints name,
+	// instead of keeping their order.
+	SortJobs bool
+}
+
+// Format canonicalizes the pipeline defined in data: sections, and the keys
+// of resources, jobs and steps follow a fixed order, resource types and
+// resources are sorted by name. Groups keep their order, which is the order
+// of their tabs. Values, e.g. sources and params, as well as comments are
+// kept untouched, so that formatting twice gives the same document.
+func Format(data string, opts FormatOptions) (string, error) {
+	doc, err := yamlnode.ParseDocument(data)
+	if err != nil {
+		return data, err
+	}
+	root := doc.Root()
+	if root == nil {
+		return data, nil
+	}
+	// the comment heading the document is held by its first key
+	var head string
+	if len(root.Content) != 0 {
+		head = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+	yamlnode.OrderKeys(root, sectionKeys)
+	if len(root.Content) != 0 && head != "" {
+		first := root.Content[0]
+		if first.HeadComment != "" {
+			head += "\n\n" + first.HeadComment
+		}
+		first.HeadComment = head
+	}
+	for _, n := range items(root, "groups") {
+		yamlnode.OrderKeys(n, groupKeys)
+	}
+	yamlnode.SortByName(yamlnode.MappingValue(root, "resource_types"))
+	for _, n := range items(root, "resource_types") {
+		yamlnode.OrderKeys(n, resourceTypeKeys)
+	}
+	yamlnode.SortByName(yamlnode.MappingValue(root, "resources"))
+	for _, n := range items(root, "resources") {
+		yamlnode.OrderKeys(n, resourceKeys)
+	}
+	for _, n := range items(root, "jobs") {
+		yamlnode.OrderKeys(n, jobKeys)
+		for _, s := range items(n, "plan") {
+			formatStep(s)
+		}
+		formatHooks(n)
+	}
+	if opts.SortJobs {
+		p, err := ParsePipeline(data)
+		if err != nil {
+			return data, err
+		}
+		order, err := p.TopologicalJobs()
+		if err != nil {
+			return data, err
+		}
+		sortJobs(yamlnode.MappingValue(root, "jobs"), order)
+	}
+	return doc.Encode()
+}
+
+func items(n *yaml.Node, key string) []*yaml.Node {
+	v := yamlnode.MappingValue(n, key)
+	if v == nil || v.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return v.Content
+}
+
+func formatStep(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	yamlnode.OrderKeys(n, stepKeys)
+	yamlnode.OrderKeys(yamlnode.MappingValue(n, "config"), taskConfigKeys)
+	for _, key := range []string{"aggregate", "do", "try"} {
+		for _, s := range items(n, key) {
+			formatStep(s)
+		}
+	}
+	// try holds a single step, the list form being legacy
+	if t := yamlnode.MappingValue(n, "try"); t != nil && t.Kind == yaml.MappingNode {
+		formatStep(t)
+	}
+	if p := yamlnode.MappingValue(n, "in_parallel"); p != nil {
+		steps := p.Content
+		if p.Kind == yaml.MappingNode {
+			yamlnode.OrderKeys(p, inParallelKeys)
+			steps = items(p, "steps")
+		}
+		for _, s := range steps {
+			formatStep(s)
+		}
+	}
+	formatHooks(n)
+}
+
+func formatHooks(n *yaml.Node) {
+	for _, key := range stepHookKeys {
+		if h := yamlnode.MappingValue(n, key); h != nil {
+			formatStep(h)
+		}
+	}
+}
+
+// sortJobs orders the jobs of sequence n along order, a list of job names.
+func sortJobs(n *yaml.Node, order []string) {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return
+	}
+	byName := make(map[string][]*yaml.Node)
+	var unnamed []*yaml.Node
+	for _, j := range n.Content {
+		name := ""
+		if v := yamlnode.MappingValue(j, "name"); v != nil {
+			name = v.Value
+		}
+		if name == "" {
+			unnamed = append(unnamed, j)
+			continue
+		}
+		byName[name] = append(byName[name], j)
+	}
+	var res []*yaml.Node
+	for _, name := range order {
+		res = append(res, byName[name]...)
+		delete(byName, name)
+	}
+	n.Content = append(res, unnamed...)
+}
+
+// TopologicalJobs returns the names of the jobs of p ordered so that every
+// job follows the jobs its passed constraints name, jobs keeping their order
+// otherwise. Jobs of a cycle keep their order after the jobs they depend on.
+func (p *Pipeline) TopologicalJobs() ([]string, error) {
+	var names []string
+	deps := make(map[string]map[string]bool)
+	for _, j := range p.Jobs.Jobs {
+		if _, ok := deps[j.Name]; ok {
+			continue
+		}
+		names = append(names, j.Name)
+		deps[j.Name] = make(map[string]bool)
+	}
+	for _, j := range p.Jobs.Jobs {
+		name := j.Name
+		err := j.Walk(func(path string, t job.Type, s interface{}) error {
+			if t != job.GetStepType {
+				return nil
+			}
+			st, err := job.GetGetStep(s)
+			if err != nil {
+				return err
+			}
+			for _, pj := range st.Passed {
+				if _, ok := deps[pj]; ok && pj != name {
+					deps[name][pj] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	done := make(map[string]bool)
+	var res []string
+	for len(res) < len(names) {
+		progress := false
+		for _, n := range names {
+			if done[n] {
+				continue
+			}
+			ready := true
+			for d := range deps[n] {
+				if !done[d] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				done[n] = true
+				res = append(res, n)
+				progress = true
+				break
+			}
+		}
+		if !progress {
+			// a cycle: release its first job
+			for _, n := range names {
+				if !done[n] {
+					done[n] = true
+					res = append(res, n)
+					break
+				}
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,122 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package pipeline
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		opts FormatOptions
+		data string
+		want string
+	}{
+		{
+			name: "sections and keys in canonical order",
+			data: `# pipeline
+jobs:
+- plan:
+  - trigger: true
+    get: repo
+  name: unit
+resources:
+- type: git
+  name: repo
+  source: {uri: "git@example.com:ci.git"}
+- name: img
+  type: registry-image
+`,
+			want: `# pipeline
+resources:
+- name: img
+  type: registry-image
+- name: repo
+  type: git
+  source: {uri: "git@example.com:ci.git"}
+jobs:
+- name: unit
+  plan:
+  - get: repo
+    trigger: true
+`,
+		},
+		{
+			name: "nested steps, hooks and indented sequences",
+			data: `jobs:
+  - name: build
+    plan:
+      - in_parallel:
+          fail_fast: true
+          steps:
+            - params: {image: repo/img.tar}
+              put: img
+      - try:
+          file: repo/lint.yml
+          task: lint
+    on_failure:
+      params: {text: ko}
+      put: slack
+`,
+			want: `jobs:
+  - name: build
+    plan:
+      - in_parallel:
+          steps:
+            - put: img
+              params: {image: repo/img.tar}
+          fail_fast: true
+      - try:
+          task: lint
+          file: repo/lint.yml
+    on_failure:
+      put: slack
+      params: {text: ko}
+`,
+		},
+		{
+			name: "jobs sorted after their passed constraints",
+			opts: FormatOptions{SortJobs: true},
+			data: `jobs:
+- name: deploy
+  plan:
+  - get: repo
+    passed: [unit]
+- name: unit
+  plan:
+  - get: repo
+`,
+			want: `jobs:
+- name: unit
+  plan:
+  - get: repo
+- name: deploy
+  plan:
+  - get: repo
+    passed: [unit]
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Format(tt.data, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			again, err := Format(got, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != got {
+				t.Errorf("formatting twice gives:\n%s\ninstead of:\n%s", again, got)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/group"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/resource"
 )
@@ -28,15 +29,59 @@ func (p *Pipeline) String() string {
 }
 
 func GetPipelineFromString(data string) Pipeline {
-	g := Pipeline{}
-	err := yaml.Unmarshal([]byte(data), &g)
+	g, err := ParsePipeline(data)
 	berror.CheckError(err)
 	return g
 }
 
+// ParsePipeline is the error returning counterpart of GetPipelineFromString.
+func ParsePipeline(data string) (Pipeline, error) {
+	g := Pipeline{}
+	err := yaml.Unmarshal([]byte(data), &g)
+	if err != nil {
+		return g, berror.FromYAML(err)
+	}
+	return g, nil
+}
+
+// LoadPipeline reads and parses the pipeline defined in filename.
+func LoadPipeline(filename string) (Pipeline, error) {
+	data, err := ioutils.LoadFile(filename)
+	if err != nil {
+		return Pipeline{}, err
+	}
+	g, err := ParsePipeline(data)
+	if err != nil {
+		return g, berror.WithFile(err, filename)
+	}
+	return g, nil
+}
+
 func (p *Pipeline) UpdateWith(n Pipeline) {
-	p.Resources = p.Resources.UpdateWith(n.Resources)
-	//	p.ResourceTypes.UpdateWith(n.ResourceTypes)
-	//	p.Groups.UpdateWith(n.Groups)
-	//	p.Jobs.UpdateWith(n.Jobs)
+	berror.CheckError(p.Merge(n))
+}
+
+// Merge is the error returning counterpart of UpdateWith.
+func (p *Pipeline) Merge(n Pipeline) error {
+	var err error
+	p.Resources, err = p.Resources.Merge(n.Resources)
+	if err != nil {
+		return err
+	}
+	p.ResourceTypes, err = p.ResourceTypes.Merge(n.ResourceTypes)
+	if err != nil {
+		return err
+	}
+	p.Groups = p.Groups.UpdateWith(n.Groups)
+	p.Jobs, err = p.Jobs.Merge(n.Jobs)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// MigrateAggregates replaces the deprecated aggregate steps of every job with
+// in_parallel steps.
+func (p *Pipeline) MigrateAggregates() error {
+	return p.Jobs.MigrateAggregates()
 }
@@ -0,0 +1,128 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/resource"
+)
+
+// UnresolvedError lists the resources and resource types a pipeline
+// references that neither the pipeline nor the registries define, every name
+// located by its first use.
+type UnresolvedError struct {
+	Violations []Violation
+}
+
+func (e *UnresolvedError) Error() string {
+	var lines []string
+	for _, v := range e.Violations {
+		lines = append(lines, v.String())
+	}
+	return fmt.Sprintf("%d unresolved name(s):\n%s", len(lines), strings.Join(lines, "\n"))
+}
+
+// Resolve completes p with the definitions of the resources its steps get or
+// put and its groups list, and of the custom types of its resources and
+// resource types, looked up by name in resources and types, e.g. the local
+// registries of a target. Definitions p already holds take precedence.
+// Resources and types are appended in the order of their first use. Names
+// found nowhere are reported in an *UnresolvedError.
+func (p *Pipeline) Resolve(resources []resource.Resource, types []resource.ResourceType) error {
+	v := &validator{}
+
+	defined := make(map[string]bool)
+	for _, r := range p.Resources.Resources {
+		defined[r.Name] = true
+	}
+	registry := make(map[string]resource.Resource)
+	for _, r := range resources {
+		if _, ok := registry[r.Name]; !ok {
+			registry[r.Name] = r
+		}
+	}
+	use := func(path, name string) {
+		if defined[name] {
+			return
+		}
+		defined[name] = true
+		r, ok := registry[name]
+		if !ok {
+			v.add(path, "unknown resource %q", name)
+			return
+		}
+		p.Resources.Resources = append(p.Resources.Resources, r)
+	}
+	for _, j := range p.Jobs.Jobs {
+		jpath := fmt.Sprintf("jobs[%s]", j.Name)
+		err := j.Walk(func(path string, t job.Type, s interface{}) error {
+			path = jpath + "." + path
+			switch t {
+			case job.GetStepType:
+				st, err := job.GetGetStep(s)
+				if err != nil {
+					return err
+				}
+				use(path+".get", st.ResourceName())
+			case job.PutStepType:
+				st, err := job.GetPutStep(s)
+				if err != nil {
+					return err
+				}
+				use(path+".put", st.ResourceName())
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, g := range p.Groups.Groups {
+		for _, r := range g.Resources {
+			use(fmt.Sprintf("groups[%s].resources", g.Name), r)
+		}
+	}
+
+	definedTypes := make(map[string]bool)
+	for _, rt := range p.ResourceTypes.ResourceTypes {
+		definedTypes[rt.Name] = true
+	}
+	typeRegistry := make(map[string]resource.ResourceType)
+	for _, rt := range types {
+		if _, ok := typeRegistry[rt.Name]; !ok {
+			typeRegistry[rt.Name] = rt
+		}
+	}
+	useType := func(path, name string) {
+		if definedTypes[name] || resource.IsCoreResourceType(name) {
+			return
+		}
+		definedTypes[name] = true
+		rt, ok := typeRegistry[name]
+		if !ok {
+			v.add(path, "unknown resource type %q", name)
+			return
+		}
+		p.ResourceTypes.ResourceTypes = append(p.ResourceTypes.ResourceTypes, rt)
+	}
+	for _, r := range p.Resources.Resources {
+		useType(fmt.Sprintf("resources[%s].type", r.Name), r.Type)
+	}
+	// resource types may be of a custom type themselves, the ones appended
+	// are checked in turn
+	for i := 0; i < len(p.ResourceTypes.ResourceTypes); i++ {
+		rt := p.ResourceTypes.ResourceTypes[i]
+		useType(fmt.Sprintf("resource_types[%s].type", rt.Name), rt.Type)
+	}
+
+	if len(v.violations) != 0 {
+		return &UnresolvedError{Violations: v.violations}
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package pipeline
+
+import (
+	"fmt"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/job"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/resource"
+)
+
+// Violation is a problem that would make Concourse reject the pipeline.
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", v.Path, v.Message)
+}
+
+type validator struct {
+	violations []Violation
+}
+
+func (v *validator) add(path string, format string, a ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		Path:    path,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// Validate reports every problem `fly set-pipeline` would reject: duplicate
+// names, unknown resource types, steps and passed constraints referencing
+// undefined resources or jobs, and groups referencing undefined jobs or
+// resources.
+func (p *Pipeline) Validate() []Violation {
+	v := &validator{}
+
+	resourceTypes := make(map[string]bool)
+	for i, rt := range p.ResourceTypes.ResourceTypes {
+		if resourceTypes[rt.Name] {
+			v.add(fmt.Sprintf("resource_types[%d]", i), "resource type %q is defined more than once", rt.Name)
+		}
+		resourceTypes[rt.Name] = true
+	}
+	for _, rt := range p.ResourceTypes.ResourceTypes {
+		if !resource.IsCoreResourceType(rt.Type) && !resourceTypes[rt.Type] {
+			v.add(fmt.Sprintf("resource_types[%s].type", rt.Name), "unknown resource type %q", rt.Type)
+		}
+	}
+
+	resources := make(map[string]bool)
+	for i, r := range p.Resources.Resources {
+		if resources[r.Name] {
+			v.add(fmt.Sprintf("resources[%d]", i), "resource %q is defined more than once", r.Name)
+		}
+		resources[r.Name] = true
+		if !resource.IsCoreResourceType(r.Type) && !resourceTypes[r.Type] {
+			v.add(fmt.Sprintf("resources[%s].type", r.Name), "unknown resource type %q", r.Type)
+		}
+	}
+
+	jobs := make(map[string]bool)
+	for i, j := range p.Jobs.Jobs {
+		if jobs[j.Name] {
+			v.add(fmt.Sprintf("jobs[%d]", i), "job %q is defined more than once", j.Name)
+		}
+		jobs[j.Name] = true
+	}
+
+	// resources each job gets or puts, for passed constraints
+	touched := make(map[string]map[string]bool)
+	type passed struct {
+		path     string
+		resource string
+		jobs     []string
+	}
+	var constraints []passed
+	for _, j := range p.Jobs.Jobs {
+		jpath := fmt.Sprintf("jobs[%s]", j.Name)
+		if touched[j.Name] == nil {
+			touched[j.Name] = make(map[string]bool)
+		}
+		err := j.Walk(func(path string, t job.Type, s interface{}) error {
+			path = jpath + "." + path
+			switch t {
+			case job.GetStepType:
+				st, err := job.GetGetStep(s)
+				if err != nil {
+					return err
+				}
+				name := st.ResourceName()
+				touched[j.Name][name] = true
+				if !resources[name] {
+					v.add(path+".get", "unknown resource %q", name)
+				}
+				if len(st.Passed) != 0 {
+					constraints = append(constraints, passed{path + ".passed", name, st.Passed})
+				}
+			case job.PutStepType:
+				st, err := job.GetPutStep(s)
+				if err != nil {
+					return err
+				}
+				name := st.ResourceName()
+				touched[j.Name][name] = true
+				if !resources[name] {
+					v.add(path+".put", "unknown resource %q", name)
+				}
+			case job.UnrecognizedType:
+				v.add(path, "unrecognized step")
+			}
+			return nil
+		})
+		if err != nil {
+			v.add(jpath, "%v", err)
+		}
+	}
+	for _, c := range constraints {
+		for _, pj := range c.jobs {
+			if !jobs[pj] {
+				v.add(c.path, "unknown job %q", pj)
+			} else if !touched[pj][c.resource] {
+				v.add(c.path, "job %q does not get or put resource %q", pj, c.resource)
+			}
+		}
+	}
+
+	for _, g := range p.Groups.Groups {
+		gpath := fmt.Sprintf("groups[%s]", g.Name)
+		for _, gj := range g.Jobs {
+			if !jobs[gj] {
+				v.add(gpath+".jobs", "unknown job %q", gj)
+			}
+		}
+		for _, gr := range g.Resources {
+			if !resources[gr] {
+				v.add(gpath+".resources", "unknown resource %q", gr)
+			}
+		}
+	}
+	return v.violations
+}
+
+// CheckArtifacts reports the steps of the jobs consuming artifacts no earlier
+// step produces, and the tasks mapping inputs or outputs they do not declare.
+// Tasks defined in a file are only checked when r resolves their config. The
+// warnings of the artifact flows are left out.
+func (p *Pipeline) CheckArtifacts(r job.TaskConfigResolver) []Violation {
+	v := &validator{}
+	paths := p.PathParams()
+	for _, j := range p.Jobs.Jobs {
+		jpath := fmt.Sprintf("jobs[%s]", j.Name)
+		flow, err := j.ArtifactFlow(r, paths)
+		if err != nil {
+			v.add(jpath, "%v", err)
+			continue
+		}
+		for _, p := range flow.Problems {
+			if e, ok := p.(*berror.Error); ok && e.File == "" {
+				v.add(jpath+"."+e.Path, "%v", e.Err)
+				continue
+			}
+			v.add(jpath, "%v", p)
+		}
+	}
+	return v.violations
+}
+
+// PathParams tells which put params of the resources of p hold paths inside
+// artifacts, as documented by the catalog of their type.
+func (p *Pipeline) PathParams() job.PathParams {
+	types := make(map[string]string)
+	for _, r := range p.Resources.Resources {
+		types[r.Name] = r.Type
+	}
+	return func(name, key string) bool {
+		d, ok := resource.GetTypeDoc(types[name])
+		return ok && d.PathParam(key)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package registry
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultNamespace holds the versioned components referenced without a
+// namespace, e.g. "git-repo@1.2".
+const DefaultNamespace = "default"
+
+// Ref addresses a component of the registry as namespace/name@version. The
+// namespace and the version are optional: a reference carrying neither
+// designates a component of the flat --target layout, an empty version the
+// latest release and an empty namespace the DefaultNamespace.
+type Ref struct {
+	Namespace string
+	Name      string
+	Version   string
+}
+
+// ParseRef parses a reference such as "git-repo", "git-repo@1.2",
+// "team-a/git-repo" or "team-a/git-repo@1.2". The version may be a
+// constraint, see Version.Matches. A versioned reference without namespace
+// is placed in the DefaultNamespace.
+func ParseRef(s string) (Ref, error) {
+	res := Ref{}
+	name := s
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		name, res.Version = s[:i], s[i+1:]
+		if res.Version == "" {
+			return res, fmt.Errorf("invalid reference %q: empty version", s)
+		}
+	}
+	parts := strings.Split(name, "/")
+	switch len(parts) {
+	case 1:
+		res.Name = parts[0]
+		if res.Version != "" {
+			res.Namespace = DefaultNamespace
+		}
+	case 2:
+		res.Namespace, res.Name = parts[0], parts[1]
+		if res.Namespace == "" {
+			return res, fmt.Errorf("invalid reference %q: empty namespace", s)
+		}
+	default:
+		return res, fmt.Errorf("invalid reference %q: expected namespace/name@version", s)
+	}
+	if err := res.Validate(); err != nil {
+		return res, fmt.Errorf("invalid reference %q: %v", s, err)
+	}
+	return res, nil
+}
+
+// Validate checks that the namespace and the name of r are single path
+// segments, so that r designates a folder of the registry.
+func (r Ref) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("empty name")
+	}
+	for _, s := range []string{r.Namespace, r.Name} {
+		if s == "." || s == ".." || strings.ContainsAny(s, "/\\:") {
+			return fmt.Errorf("%q is not a valid namespace or name", s)
+		}
+	}
+	return nil
+}
+
+func (r Ref) String() string {
+	res := r.Name
+	if r.Namespace != "" {
+		res = r.Namespace + "/" + res
+	}
+	if r.Version != "" {
+		res += "@" + r.Version
+	}
+	return res
+}
+
+// IsLocal tells whether r designates a component of the flat layout, i.e.
+// it carries neither a namespace nor a version.
+func (r Ref) IsLocal() bool {
+	return r.Namespace == "" && r.Version == ""
+}
+
+// Version is a semantic version, MAJOR.MINOR.PATCH with an optional
+// pre-release suffix. Build metadata is ignored.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Pre   string
+}
+
+func ParseVersion(s string) (Version, error) {
+	res := Version{}
+	v := strings.TrimPrefix(s, "v")
+	if i := strings.Index(v, "+"); i >= 0 {
+		v = v[:i]
+	}
+	if i := strings.Index(v, "-"); i >= 0 {
+		v, res.Pre = v[:i], v[i+1:]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return res, fmt.Errorf("invalid version %q: expected MAJOR.MINOR.PATCH", s)
+	}
+	nums := []*int{&res.Major, &res.Minor, &res.Patch}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return res, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+	}
+	return res, nil
+}
+
+func (v Version) String() string {
+	res := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Pre != "" {
+		res += "-" + v.Pre
+	}
+	return res
+}
+
+// Less orders versions by precedence, a pre-release preceding its release.
+func (v Version) Less(o Version) bool {
+	if v.Major != o.Major {
+		return v.Major < o.Major
+	}
+	if v.Minor != o.Minor {
+		return v.Minor < o.Minor
+	}
+	if v.Patch != o.Patch {
+		return v.Patch < o.Patch
+	}
+	if v.Pre == "" || o.Pre == "" {
+		return v.Pre != "" && o.Pre == ""
+	}
+	return lessPre(v.Pre, o.Pre)
+}
+
+// lessPre compares pre-releases as semver does: dot-separated identifiers
+// one by one, numeric ones numerically and before alphanumeric ones, which
+// compare lexically. A prefix precedes the longer pre-release.
+func lessPre(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aerr := strconv.ParseUint(as[i], 10, 64)
+		bn, berr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aerr == nil && berr == nil:
+			return an < bn
+		case aerr == nil || berr == nil:
+			return aerr == nil
+		default:
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// Matches tells whether v satisfies constraint c, which is one of:
+//   - "" or "latest": any release
+//   - "1.2.3": this exact version
+//   - "1", "1.2", "1.x" or "1.2.x": any release with this prefix
+//   - "^1.2.3": any release from 1.2.3 with major version 1
+//   - "~1.2.3": any release from 1.2.3 with minor version 1.2
+//
+// Pre-releases only match a constraint naming them exactly.
+func (v Version) Matches(c string) (bool, error) {
+	if c == "" || c == "latest" {
+		return v.Pre == "", nil
+	}
+	if strings.HasPrefix(c, "^") || strings.HasPrefix(c, "~") {
+		min, err := ParseVersion(c[1:])
+		if err != nil {
+			return false, err
+		}
+		if v.Pre != "" || v.Less(min) || v.Major != min.Major {
+			return false, nil
+		}
+		return c[0] == '^' || v.Minor == min.Minor, nil
+	}
+	if exact, err := ParseVersion(c); err == nil {
+		return v == exact, nil
+	}
+	if v.Pre != "" {
+		return false, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(c, "v"), ".")
+	if len(parts) > 3 {
+		return false, fmt.Errorf("invalid version constraint %q", c)
+	}
+	nums := []int{v.Major, v.Minor, v.Patch}
+	for i, p := range parts {
+		if p == "x" || p == "*" {
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return false, fmt.Errorf("invalid version constraint %q", c)
+		}
+		if n != nums[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,164 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want Ref
+		err  bool
+	}{
+		{ref: "git-repo", want: Ref{Name: "git-repo"}},
+		{ref: "git-repo@1.2", want: Ref{Namespace: DefaultNamespace, Name: "git-repo", Version: "1.2"}},
+		{ref: "team-a/git-repo", want: Ref{Namespace: "team-a", Name: "git-repo"}},
+		{ref: "team-a/git-repo@^1.2.0", want: Ref{Namespace: "team-a", Name: "git-repo", Version: "^1.2.0"}},
+		{ref: "git-repo@", err: true},
+		{ref: "/git-repo@1.0.0", err: true},
+		{ref: "team-a/@1.0.0", err: true},
+		{ref: "a/b/git-repo@1.0.0", err: true},
+		{ref: "../git-repo@1.0.0", err: true},
+		{ref: "team-a/..@1.0.0", err: true},
+		{ref: `..\git-repo@1.0.0`, err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseRef(tt.ref)
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionLess(t *testing.T) {
+	// sorted by precedence, as in the semver specification
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0-rc.2",
+		"1.0.0-rc.10",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, err := ParseVersion(ordered[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ParseVersion(ordered[j])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := a.Less(b), i < j; got != want {
+				t.Errorf("%s.Less(%s) = %t, want %t", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestVersionMatches(t *testing.T) {
+	tests := []struct {
+		version    string
+		constraint string
+		want       bool
+	}{
+		{"1.2.3", "", true},
+		{"1.2.3-rc.1", "latest", false},
+		{"1.2.3", "1.2.3", true},
+		{"1.2.3", "1.x", true},
+		{"1.2.3", "1.3", false},
+		{"1.4.0", "^1.2.3", true},
+		{"2.0.0", "^1.2.3", false},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"1.2.3-rc.1", "1.x", false},
+		{"1.2.3-rc.1", "1.2.3-rc.1", true},
+	}
+	for _, tt := range tests {
+		v, err := ParseVersion(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := v.Matches(tt.constraint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s matches %q = %t, want %t", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	r := New(dir)
+	for _, v := range []string{"1.0.0-rc.2", "1.0.0-rc.10", "1.0.0", "1.1.0"} {
+		err := r.Publish(StepsKind, Ref{Name: "unit", Version: v}, "task: unit", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"unit@1.0", "default/unit@1.0.0"},
+		{"unit@latest", "default/unit@1.1.0"},
+		{"default/unit@1.x", "default/unit@1.1.0"},
+	}
+	for _, tt := range tests {
+		ref, err := ParseRef(tt.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := r.Resolve(StepsKind, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != tt.want {
+			t.Errorf("%s resolved to %s, want %s", tt.ref, got, tt.want)
+		}
+	}
+	versions, err := r.Versions(StepsKind, DefaultNamespace, "unit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := joinVersions(versions); got != "1.0.0-rc.2, 1.0.0-rc.10, 1.0.0, 1.1.0" {
+		t.Errorf("got versions %s", got)
+	}
+	err = r.Publish(StepsKind, Ref{Namespace: "..", Name: "unit", Version: "1.0.0"}, "task: unit", false)
+	if err == nil {
+		t.Error("published outside of the registry")
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+)
+
+const (
+	StepsKind      = "steps"
+	DecoratorsKind = "decorators"
+	JobsKind       = "jobs"
+
+	registryDir = "registry"
+	ext         = ".yml"
+)
+
+var Kinds = []string{StepsKind, DecoratorsKind, JobsKind}
+
+// Registry stores versioned components under a target folder, next to the
+// flat layout:
+//
+//	<target>/registry/<namespace>/<kind>/<name>/<version>.yml
+//
+// each file holding the definition of a single component.
+type Registry struct {
+	root string
+}
+
+func New(target string) *Registry {
+	return &Registry{root: filepath.Join(target, registryDir)}
+}
+
+func (r *Registry) dir(kind, namespace, name string) string {
+	return filepath.Join(r.root, namespace, kind, name)
+}
+
+// Path returns the file holding the exact version ref.
+func (r *Registry) Path(kind string, ref Ref) string {
+	return filepath.Join(r.dir(kind, ref.Namespace, ref.Name), ref.Version+ext)
+}
+
+// Versions returns the versions of a component, sorted by precedence.
+func (r *Registry) Versions(kind string, namespace, name string) ([]Version, error) {
+	var res []Version
+	files, err := ioutil.ReadDir(r.dir(kind, namespace, name))
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != ext {
+			continue
+		}
+		v, err := ParseVersion(strings.TrimSuffix(f.Name(), ext))
+		if err != nil {
+			return res, berror.WithFile(err, filepath.Join(r.dir(kind, namespace, name), f.Name()))
+		}
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Less(res[j]) })
+	return res, nil
+}
+
+// Resolve returns ref with its version constraint replaced by the highest
+// stored version satisfying it.
+func (r *Registry) Resolve(kind string, ref Ref) (Ref, error) {
+	ref, err := withNamespace(kind, ref)
+	if err != nil {
+		return ref, err
+	}
+	versions, err := r.Versions(kind, ref.Namespace, ref.Name)
+	if err != nil {
+		return ref, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		ok, err := versions[i].Matches(ref.Version)
+		if err != nil {
+			return ref, err
+		}
+		if ok {
+			res := ref
+			res.Version = versions[i].String()
+			return res, nil
+		}
+	}
+	if len(versions) == 0 {
+		return ref, fmt.Errorf("%s %s not found in registry", kind, ref)
+	}
+	return ref, fmt.Errorf("%s %s: no version matches, available: %s", kind, ref, joinVersions(versions))
+}
+
+// Load resolves ref and returns the resolved reference along with the
+// definition it points at.
+func (r *Registry) Load(kind string, ref Ref) (Ref, string, error) {
+	res, err := r.Resolve(kind, ref)
+	if err != nil {
+		return res, "", err
+	}
+	data, err := ioutils.LoadFile(r.Path(kind, res))
+	return res, data, err
+}
+
+// Publish stores the definition data as the exact version ref. Published
+// versions are immutable: overwriting one fails unless force is set.
+func (r *Registry) Publish(kind string, ref Ref, data string, force bool) error {
+	ref, err := withNamespace(kind, ref)
+	if err != nil {
+		return err
+	}
+	v, err := ParseVersion(ref.Version)
+	if err != nil {
+		return err
+	}
+	ref.Version = v.String()
+	filename := r.Path(kind, ref)
+	if _, err := os.Stat(filename); err == nil && !force {
+		return fmt.Errorf("%s %s is already published", kind, ref)
+	}
+	err = ioutils.EnsureDir(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(data), 0644)
+}
+
+// List returns every stored version of the components of kind, sorted by
+// namespace, name and version.
+func (r *Registry) List(kind string) ([]Ref, error) {
+	var res []Ref
+	namespaces, err := ioutil.ReadDir(r.root)
+	if os.IsNotExist(err) {
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	for _, ns := range namespaces {
+		if !ns.IsDir() {
+			continue
+		}
+		names, err := ioutil.ReadDir(filepath.Join(r.root, ns.Name(), kind))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+		for _, n := range names {
+			if !n.IsDir() {
+				continue
+			}
+			versions, err := r.Versions(kind, ns.Name(), n.Name())
+			if err != nil {
+				return res, err
+			}
+			for _, v := range versions {
+				res = append(res, Ref{Namespace: ns.Name(), Name: n.Name(), Version: v.String()})
+			}
+		}
+	}
+	return res, nil
+}
+
+// withNamespace places ref in the DefaultNamespace when it has none, and
+// checks it designates a folder of the registry.
+func withNamespace(kind string, ref Ref) (Ref, error) {
+	if ref.Namespace == "" {
+		ref.Namespace = DefaultNamespace
+	}
+	if err := ref.Validate(); err != nil {
+		return ref, fmt.Errorf("%s %s: %v", kind, ref, err)
+	}
+	return ref, nil
+}
+
+func joinVersions(versions []Version) string {
+	var res []string
+	for _, v := range versions {
+		res = append(res, v.String())
+	}
+	return strings.Join(res, ", ")
+}
@@ -0,0 +1,562 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// FieldDoc documents a field of the source of a resource type, or of the
+// params of its get and put steps.
+type FieldDoc struct {
+	Name        string
+	Required    bool
+	Description string
+	// Path is set on params holding paths inside the artifacts of the job,
+	// e.g. the file an s3 resource uploads.
+	Path bool
+}
+
+// TypeDoc documents a resource type.
+type TypeDoc struct {
+	Name        string
+	Description string
+	Source      []FieldDoc
+	// params of get steps, and get_params of put steps
+	GetParams []FieldDoc
+	// params of put steps
+	Params []FieldDoc
+	// Core is set on the resource types shipped with Concourse workers, which
+	// can be used without being declared in resource_types.
+	Core bool
+}
+
+// SourceField returns the documentation of source field name.
+func (t *TypeDoc) SourceField(name string) (FieldDoc, bool) {
+	for _, f := range t.Source {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return FieldDoc{}, false
+}
+
+// PathParam tells whether put param name holds paths inside artifacts.
+func (t *TypeDoc) PathParam(name string) bool {
+	for _, f := range t.Params {
+		if f.Name == name {
+			return f.Path
+		}
+	}
+	return false
+}
+
+func (t *TypeDoc) String() string {
+	var b bytes.Buffer
+	kind := "custom"
+	if t.Core {
+		kind = "core"
+	}
+	fmt.Fprintf(&b, "%s (%s): %s\n", t.Name, kind, t.Description)
+	for _, s := range []struct {
+		title  string
+		fields []FieldDoc
+	}{
+		{"source", t.Source},
+		{"get params", t.GetParams},
+		{"put params", t.Params},
+	} {
+		if len(s.fields) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "  %s:\n", s.title)
+		for _, f := range s.fields {
+			name := f.Name
+			if f.Required {
+				name += " (required)"
+			}
+			fmt.Fprintf(&b, "    %-32s %s\n", name, f.Description)
+		}
+	}
+	return b.String()
+}
+
+var catalog = make(map[string]TypeDoc)
+
+// RegisterTypeDoc adds the documentation of a resource type to the catalog,
+// replacing any previous one.
+func RegisterTypeDoc(d TypeDoc) {
+	catalog[d.Name] = d
+}
+
+func GetTypeDoc(t string) (TypeDoc, bool) {
+	d, ok := catalog[t]
+	return d, ok
+}
+
+// CatalogTypes returns the resource types documented in the catalog, sorted.
+func CatalogTypes() []string {
+	var res []string
+	for t := range catalog {
+		res = append(res, t)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// UnknownSourceFields returns the fields of source, the source of a resource
+// of type t, that neither the catalog nor the source schema of t know, sorted.
+// Sources of types missing from the catalog are not checked.
+func UnknownSourceFields(t string, source interface{}) ([]string, error) {
+	d, ok := GetTypeDoc(t)
+	if !ok {
+		return nil, nil
+	}
+	m, err := sourceMap(source)
+	if err != nil {
+		return nil, err
+	}
+	s, _ := GetSourceSchema(t)
+	var res []string
+	for k := range m {
+		name := fmt.Sprint(k)
+		if _, ok := d.SourceField(name); ok {
+			continue
+		}
+		if _, ok := s.Field(name); ok {
+			continue
+		}
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// UnknownSourceFields returns the fields of the source of r the catalog does
+// not document for its type.
+func (r *Resource) UnknownSourceFields() ([]string, error) {
+	return UnknownSourceFields(r.Type, r.Source)
+}
+
+func doc(name, description string) FieldDoc {
+	return FieldDoc{Name: name, Description: description}
+}
+
+func required(name, description string) FieldDoc {
+	return FieldDoc{Name: name, Required: true, Description: description}
+}
+
+func (f FieldDoc) path() FieldDoc {
+	f.Path = true
+	return f
+}
+
+func init() {
+	RegisterTypeDoc(TypeDoc{
+		Name:        "bosh-io-release",
+		Core:        true,
+		Description: "tracks versions of a release on bosh.io",
+		Source: []FieldDoc{
+			required("repository", "GitHub repository of the release, e.g. cloudfoundry/bosh"),
+			doc("regexp", "only track versions matching this regular expression"),
+		},
+		GetParams: []FieldDoc{
+			doc("tarball", "fetch the release tarball, true by default"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        BoshIOStemcellResourceType,
+		Core:        true,
+		Description: "tracks versions of a stemcell on bosh.io",
+		Source: []FieldDoc{
+			required("name", "name of the stemcell"),
+			doc("force_regular", "fetch regular stemcells even when light ones exist"),
+		},
+		GetParams: []FieldDoc{
+			doc("tarball", "fetch the stemcell tarball, true by default"),
+			doc("preserve_filename", "keep the original file name of the tarball"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        "cf",
+		Core:        true,
+		Description: "deploys an application to Cloud Foundry",
+		Source: []FieldDoc{
+			required("api", "address of the Cloud Controller"),
+			required("organization", "organization to push to"),
+			required("space", "space to push to"),
+			doc("username", "user to authenticate as"),
+			doc("password", "password of the user"),
+			doc("client_id", "client to authenticate as, instead of a user"),
+			doc("client_secret", "secret of the client"),
+			doc("skip_cert_check", "do not check the certificate of the api"),
+			doc("verbose", "log the requests made to the api"),
+		},
+		Params: []FieldDoc{
+			required("manifest", "path to the application manifest").path(),
+			doc("path", "path to the application bits").path(),
+			doc("current_app_name", "name of the app to replace with a zero-downtime push"),
+			doc("environment_variables", "environment variables added to the manifest"),
+			doc("vars", "variables interpolated in the manifest"),
+			doc("vars_files", "files of variables interpolated in the manifest").path(),
+			doc("docker_username", "user of the docker registry"),
+			doc("docker_password", "password of the docker registry"),
+			doc("show_app_log", "print the application log on failure"),
+			doc("no_start", "do not start the application"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        DockerImageResourceType,
+		Core:        true,
+		Description: "builds, pushes and fetches docker images",
+		Source: []FieldDoc{
+			required("repository", "name of the repository, e.g. concourse/concourse"),
+			doc("tag", "tag to track, latest by default"),
+			doc("username", "user of the registry"),
+			doc("password", "password of the registry"),
+			doc("aws_access_key_id", "AWS access key to authenticate with ECR"),
+			doc("aws_secret_access_key", "AWS secret key to authenticate with ECR"),
+			doc("aws_session_token", "AWS session token to authenticate with ECR"),
+			doc("insecure_registries", "registries to access over plain HTTP"),
+			doc("registry_mirror", "registry mirror to pull from"),
+			doc("ca_certs", "certificates of the registries, per domain"),
+			doc("client_certs", "client certificates and keys, per domain"),
+			doc("max_concurrent_downloads", "maximum concurrent layer downloads"),
+			doc("max_concurrent_uploads", "maximum concurrent layer uploads"),
+		},
+		GetParams: []FieldDoc{
+			doc("save", "save the image with docker save"),
+			doc("rootfs", "export the image as a rootfs tarball"),
+			doc("skip_download", "only fetch the metadata of the image"),
+		},
+		Params: []FieldDoc{
+			doc("build", "directory holding the Dockerfile to build").path(),
+			doc("dockerfile", "path to the Dockerfile, build/Dockerfile by default").path(),
+			doc("build_args", "build arguments"),
+			doc("build_args_file", "file holding build arguments").path(),
+			doc("target_name", "target stage of a multi-stage build"),
+			doc("cache", "use the pushed image as build cache"),
+			doc("cache_from", "images to use as build cache"),
+			doc("cache_tag", "tag of the image used as build cache"),
+			doc("load", "directory holding an image saved with docker save").path(),
+			doc("load_base", "directory holding the base image to load before building").path(),
+			doc("load_bases", "directories holding base images to load before building").path(),
+			doc("load_file", "file holding an image to load").path(),
+			doc("load_repository", "repository of the image to load"),
+			doc("load_tag", "tag of the image to load"),
+			doc("import_file", "rootfs tarball to import").path(),
+			doc("pull_repository", "repository to pull the image from"),
+			doc("pull_tag", "tag to pull the image from"),
+			doc("tag_file", "file holding the tag to push").path(),
+			doc("tag_as_latest", "also push the image as latest"),
+			doc("tag_prefix", "prefix of the tag read from tag_file"),
+			doc("additional_tags", "file holding other tags to push").path(),
+			doc("labels", "labels of the image"),
+			doc("labels_file", "file holding labels of the image").path(),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        GitResourceType,
+		Core:        true,
+		Description: "tracks commits of a branch of a git repository",
+		Source: []FieldDoc{
+			required("uri", "location of the repository"),
+			doc("branch", "branch to track, the default branch of the repository otherwise"),
+			doc("private_key", "SSH key to authenticate with"),
+			doc("private_key_user", "user of the SSH key"),
+			doc("private_key_passphrase", "passphrase of the SSH key"),
+			doc("forward_agent", "forward the SSH agent"),
+			doc("username", "user to authenticate with over HTTP(S)"),
+			doc("password", "password to authenticate with over HTTP(S)"),
+			doc("paths", "only track commits changing these paths"),
+			doc("ignore_paths", "ignore commits only changing these paths"),
+			doc("skip_ssl_verification", "do not check the certificate of the repository"),
+			doc("tag_filter", "only track commits with a tag matching this glob"),
+			doc("tag_regex", "only track commits with a tag matching this regular expression"),
+			doc("fetch_tags", "fetch tags"),
+			doc("git_config", "git configuration set before running git"),
+			doc("disable_ci_skip", "do not skip commits marked with [ci skip]"),
+			doc("commit_verification_keys", "GPG keys commits must be signed with"),
+			doc("commit_verification_key_ids", "ids of the GPG keys commits must be signed with"),
+			doc("gpg_keyserver", "keyserver to fetch commit_verification_key_ids from"),
+			doc("git_crypt_key", "base64 encoded git-crypt key to decrypt the repository with"),
+			doc("https_tunnel", "proxy to tunnel connections through"),
+			doc("commit_filter", "only track commits which message matches or excludes patterns"),
+			doc("version_depth", "number of commits returned by checks"),
+			doc("search_remote_refs", "also look for versions in refs other than branch"),
+		},
+		GetParams: []FieldDoc{
+			doc("depth", "depth of the clone"),
+			doc("fetch_tags", "fetch tags"),
+			doc("submodules", "submodules to fetch, all or none"),
+			doc("submodule_recursive", "fetch submodules recursively"),
+			doc("submodule_remote", "fetch the latest commit of submodules"),
+			doc("disable_git_lfs", "do not fetch git LFS files"),
+			doc("clean_tags", "remove tags from the clone"),
+			doc("short_ref_format", "format of the short ref file"),
+			doc("timestamp_format", "format of the commit timestamp file"),
+			doc("describe_ref_options", "options passed to git describe"),
+		},
+		Params: []FieldDoc{
+			required("repository", "path to the repository to push").path(),
+			doc("rebase", "rebase on the remote branch when the push is rejected"),
+			doc("merge", "merge the remote branch when the push is rejected"),
+			doc("returning", "version returned after a merge, merged or unmerged"),
+			doc("tag", "file holding a tag to add to the pushed commit").path(),
+			doc("only_tag", "only push the tag"),
+			doc("tag_prefix", "prefix of the tag"),
+			doc("force", "force push"),
+			doc("annotate", "file holding the annotation of the tag").path(),
+			doc("notes", "file holding notes to add to the commit").path(),
+			doc("branch", "branch to push to, instead of the tracked one"),
+			doc("refs_prefix", "prefix of the refs to push to"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        GithubReleaseResourceType,
+		Core:        true,
+		Description: "fetches and creates releases of a GitHub repository",
+		Source: []FieldDoc{
+			required("owner", "owner of the repository"),
+			required("repository", "name of the repository"),
+			doc("access_token", "token to authenticate with"),
+			doc("github_api_url", "address of the API, for GitHub Enterprise"),
+			doc("github_uploads_url", "address of the uploads API, for GitHub Enterprise"),
+			doc("insecure", "do not check the certificate of the API"),
+			doc("release", "track releases"),
+			doc("pre_release", "track pre-releases"),
+			doc("drafts", "track draft releases"),
+			doc("semver_constraint", "only track versions satisfying this constraint"),
+			doc("tag_filter", "only track tags matching this regular expression"),
+			doc("order_by", "order releases by version or time"),
+		},
+		GetParams: []FieldDoc{
+			doc("globs", "only fetch assets matching these globs"),
+			doc("include_source_tarball", "fetch the source tarball"),
+			doc("include_source_zip", "fetch the source zip"),
+		},
+		Params: []FieldDoc{
+			required("name", "file holding the name of the release").path(),
+			required("tag", "file holding the tag of the release").path(),
+			doc("tag_prefix", "prefix of the tag"),
+			doc("commitish", "file holding the commit to tag").path(),
+			doc("body", "file holding the description of the release").path(),
+			doc("globs", "assets to upload").path(),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        "hg",
+		Core:        true,
+		Description: "tracks commits of a branch of a mercurial repository",
+		Source: []FieldDoc{
+			required("uri", "location of the repository"),
+			doc("branch", "branch to track, default by default"),
+			doc("private_key", "SSH key to authenticate with"),
+			doc("paths", "only track commits changing these paths"),
+			doc("ignore_paths", "ignore commits only changing these paths"),
+			doc("skip_ssl_verification", "do not check the certificate of the repository"),
+			doc("tag_filter", "only track commits with a tag matching this regular expression"),
+			doc("revset_filter", "only track commits matching this revset"),
+		},
+		Params: []FieldDoc{
+			required("repository", "path to the repository to push").path(),
+			doc("rebase", "rebase on the remote branch when the push is rejected"),
+			doc("tag", "file holding a tag to add to the pushed commit").path(),
+			doc("tag_prefix", "prefix of the tag"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        "mock",
+		Core:        true,
+		Description: "emits versions for testing pipelines",
+		Source: []FieldDoc{
+			doc("initial_version", "version emitted by the first check"),
+			doc("no_initial_version", "do not emit a version on the first check"),
+			doc("force_version", "version emitted by every check"),
+			doc("create_files", "files created by get steps"),
+			doc("mirror_self", "fetch the mock resource type image itself"),
+			doc("log", "message printed by every step"),
+		},
+		GetParams: []FieldDoc{
+			doc("create_files_via_params", "files created by the step"),
+			doc("mirror_self_via_params", "fetch the mock resource type image itself"),
+		},
+		Params: []FieldDoc{
+			doc("version", "version to emit"),
+			doc("print_env", "print the environment of the step"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        PoolResourceType,
+		Core:        true,
+		Description: "manages locks of a pool stored in a git repository",
+		Source: []FieldDoc{
+			required("uri", "location of the repository"),
+			required("branch", "branch of the repository"),
+			required("pool", "folder of the pool in the repository"),
+			doc("private_key", "SSH key to authenticate with"),
+			doc("username", "user to authenticate with over HTTP(S)"),
+			doc("password", "password to authenticate with over HTTP(S)"),
+			doc("retry_delay", "delay between attempts to acquire a lock"),
+			doc("git_config", "git configuration set before running git"),
+		},
+		Params: []FieldDoc{
+			doc("acquire", "acquire any available lock"),
+			doc("claim", "name of the lock to acquire"),
+			doc("release", "path to a lock to release").path(),
+			doc("add", "path to a lock to add").path(),
+			doc("add_claimed", "path to a lock to add acquired").path(),
+			doc("remove", "path to a lock to remove").path(),
+			doc("update", "path to a lock to update").path(),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        RegistryImageResourceType,
+		Core:        true,
+		Description: "fetches and pushes OCI images, the replacement of docker-image",
+		Source: []FieldDoc{
+			required("repository", "name of the repository, e.g. concourse/concourse"),
+			doc("tag", "tag to track, latest by default"),
+			doc("variant", "suffix of the tags to track"),
+			doc("semver_constraint", "only track tags satisfying this constraint"),
+			doc("tag_regex", "only track tags matching this regular expression"),
+			doc("pre_releases", "track pre-release tags"),
+			doc("created_at_sort", "order tags by creation date"),
+			doc("username", "user of the registry"),
+			doc("password", "password of the registry"),
+			doc("aws_access_key_id", "AWS access key to authenticate with ECR"),
+			doc("aws_secret_access_key", "AWS secret key to authenticate with ECR"),
+			doc("aws_session_token", "AWS session token to authenticate with ECR"),
+			doc("aws_region", "AWS region of the ECR registry"),
+			doc("aws_role_arn", "AWS role to assume"),
+			doc("aws_role_arns", "AWS roles to assume in turn"),
+			doc("registry_mirror", "registry mirror to pull from"),
+			doc("content_trust", "Docker content trust configuration"),
+			doc("ca_certs", "certificates of the registry"),
+			doc("insecure", "access the registry over plain HTTP"),
+			doc("platform", "platform of the image to fetch"),
+			doc("debug", "log debug output"),
+		},
+		GetParams: []FieldDoc{
+			doc("format", "format of the image fetched, rootfs or oci"),
+			doc("skip_download", "only fetch the metadata of the image"),
+		},
+		Params: []FieldDoc{
+			required("image", "path to the OCI image tarball to push").path(),
+			doc("version", "version of the image, tagged along with its variant"),
+			doc("bump_aliases", "also push the image as the aliases of version"),
+			doc("additional_tags", "file holding other tags to push").path(),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        S3ResourceType,
+		Core:        true,
+		Description: "versions objects of an S3 bucket",
+		Source: []FieldDoc{
+			required("bucket", "name of the bucket"),
+			doc("regexp", "pattern of the object paths, versioned by their captured version"),
+			doc("versioned_file", "path of an object versioned by the bucket"),
+			doc("access_key_id", "AWS access key"),
+			doc("secret_access_key", "AWS secret key"),
+			doc("session_token", "AWS session token"),
+			doc("aws_role_arn", "AWS role to assume"),
+			doc("region_name", "region of the bucket"),
+			doc("private", "do not return the public URL of the objects"),
+			doc("cloudfront_url", "CloudFront distribution of the bucket"),
+			doc("endpoint", "endpoint of an S3 compatible store"),
+			doc("disable_ssl", "access the store over plain HTTP"),
+			doc("skip_ssl_verification", "do not check the certificate of the store"),
+			doc("skip_download", "only fetch the metadata of the objects"),
+			doc("server_side_encryption", "server side encryption of uploaded objects"),
+			doc("sse_kms_key_id", "KMS key encrypting uploaded objects"),
+			doc("use_v2_signing", "use signature v2"),
+			doc("disable_multipart", "disable multipart uploads"),
+			doc("initial_path", "version emitted before any object exists, with regexp"),
+			doc("initial_version", "version emitted before any object exists, with versioned_file"),
+			doc("initial_content_text", "content of the initial version"),
+			doc("initial_content_binary", "base64 encoded content of the initial version"),
+		},
+		GetParams: []FieldDoc{
+			doc("skip_download", "only fetch the metadata of the object"),
+			doc("unpack", "unpack the object when it is an archive"),
+			doc("download_tags", "fetch the tags of the object"),
+		},
+		Params: []FieldDoc{
+			required("file", "glob of the file to upload").path(),
+			doc("acl", "canned ACL of the uploaded object"),
+			doc("content_type", "content type of the uploaded object"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        SemverResourceType,
+		Core:        true,
+		Description: "manages a semantic version stored in git, s3, gcs or swift",
+		Source: []FieldDoc{
+			required("driver", "backend storing the version: git, s3, gcs or swift"),
+			doc("initial_version", "version used when none is stored, 0.0.0 by default"),
+			doc("uri", "git: location of the repository"),
+			doc("branch", "git: branch of the repository"),
+			doc("file", "git: file holding the version"),
+			doc("private_key", "git: SSH key to authenticate with"),
+			doc("username", "git: user to authenticate with over HTTP(S)"),
+			doc("password", "git: password to authenticate with over HTTP(S)"),
+			doc("git_user", "git: author of the commits"),
+			doc("depth", "git: depth of the clone"),
+			doc("commit_message", "git: message of the commits"),
+			doc("bucket", "s3, gcs: name of the bucket"),
+			doc("key", "s3, gcs: key of the object holding the version"),
+			doc("access_key_id", "s3: AWS access key"),
+			doc("secret_access_key", "s3: AWS secret key"),
+			doc("session_token", "s3: AWS session token"),
+			doc("region_name", "s3: region of the bucket"),
+			doc("endpoint", "s3: endpoint of an S3 compatible store"),
+			doc("disable_ssl", "s3: access the store over plain HTTP"),
+			doc("skip_ssl_verification", "git, s3: do not check certificates"),
+			doc("server_side_encryption", "s3: server side encryption of the object"),
+			doc("use_v2_signing", "s3: use signature v2"),
+			doc("json_key", "gcs: service account key"),
+			doc("openstack", "swift: OpenStack configuration"),
+		},
+		GetParams: []FieldDoc{
+			doc("bump", "bump the version fetched: major, minor, patch or final"),
+			doc("pre", "bump the version fetched to a pre-release, e.g. rc"),
+		},
+		Params: []FieldDoc{
+			doc("file", "file holding the version to store").path(),
+			doc("bump", "bump the stored version: major, minor, patch or final"),
+			doc("pre", "bump the stored version to a pre-release, e.g. rc"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        TimeResourceType,
+		Core:        true,
+		Description: "emits versions periodically or within a time range",
+		Source: []FieldDoc{
+			doc("interval", "minimum duration between versions, e.g. 1h"),
+			doc("start", "start of the daily time range, e.g. 8:00 PM"),
+			doc("stop", "end of the daily time range"),
+			doc("location", "time zone of start and stop"),
+			doc("days", "days of the week to emit versions"),
+			doc("initial_version", "emit a version on the first check"),
+		},
+	})
+	RegisterTypeDoc(TypeDoc{
+		Name:        "tracker",
+		Core:        true,
+		Description: "delivers Pivotal Tracker stories finished by commits",
+		Source: []FieldDoc{
+			required("token", "API token"),
+			required("project_id", "id of the project"),
+			doc("tracker_url", "address of Tracker"),
+		},
+		Params: []FieldDoc{
+			doc("repos", "paths to the repositories to look for finished stories").path(),
+			doc("comment", "file holding a comment added to delivered stories").path(),
+		},
+	})
+}
@@ -0,0 +1,48 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package resource
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCoreResourceTypes(t *testing.T) {
+	RegisterTypeDoc(TypeDoc{Name: "pull-request", Description: "tracks pull requests"})
+	defer delete(catalog, "pull-request")
+
+	want := []string{
+		"bosh-io-release",
+		BoshIOStemcellResourceType,
+		"cf",
+		DockerImageResourceType,
+		GitResourceType,
+		GithubReleaseResourceType,
+		"hg",
+		"mock",
+		PoolResourceType,
+		RegistryImageResourceType,
+		S3ResourceType,
+		SemverResourceType,
+		TimeResourceType,
+		"tracker",
+	}
+	if got := CoreResourceTypes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got core types %q, want %q", got, want)
+	}
+	for _, tt := range []struct {
+		t    string
+		want bool
+	}{
+		{GitResourceType, true},
+		{"pull-request", false},
+		{"slack-notification", false},
+	} {
+		if got := IsCoreResourceType(tt.t); got != tt.want {
+			t.Errorf("IsCoreResourceType(%s) = %t, want %t", tt.t, got, tt.want)
+		}
+	}
+}
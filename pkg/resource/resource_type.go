@@ -6,15 +6,17 @@ Sniperkit-Bot
 package resource
 
 import (
-	"io/ioutil"
+	"fmt"
 	"log"
 	"path/filepath"
 	"sort"
 
 	yaml "gopkg.in/yaml.v2"
 
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
 	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/yamlnode"
 )
 
 const (
@@ -23,6 +25,24 @@ const (
 	resourceTypesFile       = "resource_types.yml"
 )
 
+// CoreResourceTypes returns the resource types shipped with Concourse
+// workers, i.e. the core types of the catalog, sorted. They can be used
+// without being declared in resource_types.
+func CoreResourceTypes() []string {
+	var res []string
+	for _, t := range CatalogTypes() {
+		if IsCoreResourceType(t) {
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+func IsCoreResourceType(t string) bool {
+	d, ok := GetTypeDoc(t)
+	return ok && d.Core
+}
+
 type ResourceTypes struct {
 	ResourceTypes []ResourceType `yaml:"resource_types"`
 }
@@ -35,6 +55,50 @@ func (r *ResourceTypes) String() string {
 	return string(b[:])
 }
 
+func (r *ResourceTypes) Map() map[string]ResourceType {
+	res := make(map[string]ResourceType)
+	for _, r := range r.ResourceTypes {
+		res[r.Name] = r
+	}
+	return res
+}
+
+func (r *ResourceTypes) UpdateWith(n ResourceTypes) ResourceTypes {
+	res, err := r.Merge(n)
+	berror.CheckError(err)
+	return res
+}
+
+// Merge is the error returning counterpart of UpdateWith. Resource types are
+// matched by name, the ones only defined in n are appended.
+func (r *ResourceTypes) Merge(n ResourceTypes) (ResourceTypes, error) {
+	nm := make(map[string]ResourceType)
+	for _, nr := range n.ResourceTypes {
+		nm[nr.Name] = nr
+	}
+	seen := make(map[string]bool)
+	var res []ResourceType
+	for _, rr := range r.ResourceTypes {
+		seen[rr.Name] = true
+		if nr, ok := nm[rr.Name]; ok {
+			m, err := rr.Merge(nr)
+			if err != nil {
+				return ResourceTypes{}, berror.WithPath(err, fmt.Sprintf("resource_types[%s]", rr.Name))
+			}
+			res = append(res, m)
+		} else {
+			res = append(res, rr)
+		}
+	}
+	for _, nr := range n.ResourceTypes {
+		if !seen[nr.Name] {
+			seen[nr.Name] = true
+			res = append(res, nr)
+		}
+	}
+	return ResourceTypes{res}, nil
+}
+
 type ResourceType struct {
 	Name string `yaml:"name"`
 	Type string `yaml:"type"`
@@ -53,74 +117,124 @@ func (r *ResourceType) String() string {
 	return string(b[:])
 }
 
+func (r *ResourceType) UpdateWith(n ResourceType) ResourceType {
+	res, err := r.Merge(n)
+	berror.CheckError(err)
+	return res
+}
+
+// Merge is the error returning counterpart of UpdateWith. Fields set in n
+// override the ones of r, params and source are merged key by key.
+func (r *ResourceType) Merge(n ResourceType) (ResourceType, error) {
+	res := *r
+	if n.Type != "" && r.Type != n.Type {
+		return res, fmt.Errorf("can not update resource type:\n%+v\nwith resource type:\n%+v", r.String(), n.String())
+	}
+	if len(n.Tags) != 0 {
+		res.Tags = n.Tags
+	}
+	if n.Privileged {
+		res.Privileged = true
+	}
+	res.Params = types.MergeValues(r.Params, n.Params)
+	res.Source = types.MergeValues(r.Source, n.Source)
+	return res, nil
+}
+
 func (r *ResourceType) Equal(n ResourceType) bool {
+	res, err := r.Compare(n)
+	berror.CheckError(err)
+	return res
+}
+
+// Compare is the error returning counterpart of Equal.
+func (r *ResourceType) Compare(n ResourceType) (bool, error) {
 	if r.Name != n.Name {
-		return false
+		return false, nil
 	}
 	if r.Type != n.Type {
-		return false
+		return false, nil
 	}
 	if r.Privileged != n.Privileged {
-		return false
+		return false, nil
 	}
 	rparams, err := types.GetStringMap(r.Params)
 	if err != nil {
-		log.Fatalf("Unrecognized params: %+v\n", r.Params)
+		return false, berror.Errorf("params", "unrecognized params %+v: %v", r.Params, err)
 	}
 	nparams, err := types.GetStringMap(n.Params)
 	if err != nil {
-		log.Fatalf("Unrecognized params: %+v\n", r.Params)
+		return false, berror.Errorf("params", "unrecognized params %+v: %v", n.Params, err)
 	}
 	if !types.StringMapEqual(rparams, nparams) {
-		return false
+		return false, nil
 	}
 
 	switch r.Type {
 	case DockerImageResourceType:
 		rsource, err := GetDockerResourceTypeSource(r.Source)
 		if err != nil {
-			log.Fatalf("Unrecognized source: %+v\n", r.Source)
+			return false, berror.Errorf("source", "unrecognized source %+v: %v", r.Source, err)
 		}
 		nsource, err := GetDockerResourceTypeSource(n.Source)
 		if err != nil {
-			log.Fatalf("Unrecognized source: %+v\n", n.Source)
+			return false, berror.Errorf("source", "unrecognized source %+v: %v", n.Source, err)
 		}
 		if !rsource.Equal(nsource) {
-			return false
+			return false, nil
 		}
 	}
-	return true
+	return true, nil
 }
 
 func GetResourceTypesFromString(data string) ResourceTypes {
+	r, err := ParseResourceTypes(data)
+	berror.CheckError(err)
+	return r
+}
+
+// ParseResourceTypes is the error returning counterpart of GetResourceTypesFromString.
+func ParseResourceTypes(data string) (ResourceTypes, error) {
 	r := ResourceTypes{}
 	err := yaml.Unmarshal([]byte(data), &r)
 	if err != nil {
-		log.Fatalf("error: %v", err)
+		return r, berror.FromYAML(err)
 	}
-	return r
+	return r, nil
 }
 
+// SaveResourceTypesLocally updates the resource types file of target with res,
+// keeping the comments, order and anchors of the resource types left
+// unchanged.
 func SaveResourceTypesLocally(target string, res ResourceTypes) error {
-	err := ioutil.WriteFile(filepath.Join(target, resourceTypesDir, resourceTypesFile), []byte(res.String()), 0644)
-	if err != nil {
-		return err
-	}
-	return nil
+	return yamlnode.UpdateFile(filepath.Join(target, resourceTypesDir, resourceTypesFile), res)
 }
 
 func GetLocalResourceTypes(target string) ResourceTypeSet {
-	targetDir := filepath.Join(target, resourceTypesDir)
-	ioutils.CreateDirIfNotExist(targetDir)
-	targetFile := filepath.Join(targetDir, resourceTypesFile)
-	ioutils.CreateFileIfNotExist(targetFile)
-	content := ioutils.ReadFile(targetFile)
-	resourceTypes := GetResourceTypesFromString(content)
+	res, err := LoadLocalResourceTypes(target)
+	berror.CheckError(err)
+	return res
+}
+
+// LoadLocalResourceTypes is the error returning counterpart of GetLocalResourceTypes.
+func LoadLocalResourceTypes(target string) (ResourceTypeSet, error) {
 	res := ResourceTypeSet{}
+	targetFile := filepath.Join(target, resourceTypesDir, resourceTypesFile)
+	content, err := ioutils.LoadOrCreateFile(targetFile)
+	if err != nil {
+		return res, err
+	}
+	resourceTypes, err := ParseResourceTypes(content)
+	if err != nil {
+		return res, berror.WithFile(err, targetFile)
+	}
 	for _, rt := range resourceTypes.ResourceTypes {
-		res.Add(rt)
+		err = res.Insert(rt)
+		if err != nil {
+			return res, berror.WithFile(err, targetFile)
+		}
 	}
-	return res
+	return res, nil
 }
 
 type ResourceTypeSet struct {
@@ -132,16 +246,22 @@ func (rs *ResourceTypeSet) Get() []ResourceType {
 }
 
 func (rs *ResourceTypeSet) Add(t ResourceType) {
-	found := false
+	berror.CheckError(rs.Insert(t))
+}
+
+// Insert is the error returning counterpart of Add.
+func (rs *ResourceTypeSet) Insert(t ResourceType) error {
 	for _, r := range rs.rt {
-		if r.Equal(t) {
-			found = true
-			break
+		equal, err := r.Compare(t)
+		if err != nil {
+			return berror.WithPath(err, fmt.Sprintf("resource_types[%s]", t.Name))
+		}
+		if equal {
+			return nil
 		}
 	}
-	if !found {
-		rs.rt = append(rs.rt, t)
-	}
+	rs.rt = append(rs.rt, t)
+	return nil
 }
 
 type DockerResourceTypeSource struct {
@@ -0,0 +1,298 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+// SourceField describes a field of a resource source. Identity fields tell
+// which external object the resource tracks, e.g. the uri and branch of a
+// git repository: changing one of them makes it another resource. Other
+// fields, credentials and options, are mergeable and updated in place.
+// Secret fields hold credentials that must not be stored in plain text.
+type SourceField struct {
+	Name     string
+	Identity bool
+	Secret   bool
+}
+
+// SourceSchema lists the source fields of a resource type.
+type SourceSchema struct {
+	Fields []SourceField
+}
+
+func (s *SourceSchema) Field(name string) (SourceField, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return SourceField{}, false
+}
+
+func (s *SourceSchema) IdentityFields() []string {
+	var res []string
+	for _, f := range s.Fields {
+		if f.Identity {
+			res = append(res, f.Name)
+		}
+	}
+	return res
+}
+
+var sourceSchemas = make(map[string]SourceSchema)
+
+// RegisterSourceSchema registers the source schema of resource type t,
+// replacing any previous one.
+func RegisterSourceSchema(t string, s SourceSchema) {
+	sourceSchemas[t] = s
+}
+
+func GetSourceSchema(t string) (SourceSchema, bool) {
+	s, ok := sourceSchemas[t]
+	return s, ok
+}
+
+// customTypeImages maps the image repositories of well-known custom resource
+// types to the type their source schema is registered under.
+var customTypeImages = map[string]string{
+	"cfcommunity/slack-notification-resource":   SlackNotificationResourceType,
+	"frodenas/gcs-resource":                     GCSResourceType,
+	"samcontesse/gitlab-merge-request-resource": MergeRequestResourceType,
+}
+
+// SchemaType returns the type the source schema of resources of type t is
+// registered under. When t is a custom type declared in types, whatever its
+// name, its image repository tells, e.g. a type pulling
+// cfcommunity/slack-notification-resource is a slack-notification. Otherwise
+// t is returned as is.
+func SchemaType(t string, types []ResourceType) string {
+	for _, rt := range types {
+		if rt.Name != t {
+			continue
+		}
+		m, err := sourceMap(rt.Source)
+		if err != nil {
+			break
+		}
+		repo, _ := m["repository"].(string)
+		if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+			repo = repo[:i]
+		}
+		if st, ok := customTypeImages[repo]; ok {
+			return st
+		}
+		break
+	}
+	return t
+}
+
+// RegisteredSourceSchemas returns the resource types having a source schema,
+// sorted.
+func RegisteredSourceSchemas() []string {
+	var res []string
+	for t := range sourceSchemas {
+		res = append(res, t)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// CompareSource tells whether the sources a and b of resources of type t
+// designate the same object. When the schema of t is registered only its
+// identity fields are compared, the other ones being merged by MergeSource.
+// Sources of unregistered types are deep compared. An omitted field is equal
+// to the same field set to its zero value.
+func CompareSource(t string, a, b interface{}) (bool, error) {
+	am, err := sourceMap(a)
+	if err != nil {
+		return false, err
+	}
+	bm, err := sourceMap(b)
+	if err != nil {
+		return false, err
+	}
+	schema, ok := GetSourceSchema(t)
+	if !ok {
+		return types.ValuesEqual(am, bm), nil
+	}
+	for _, f := range schema.IdentityFields() {
+		if !types.ValuesEqual(am[f], bm[f]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// MergeSource updates the source a of a resource of type t with the fields
+// set in b. When the schema of t is registered and b sets an identity field
+// to another value, b designates another object: only the identity fields of
+// a are kept. Mergeable fields of b override the ones of a, and fields missing
+// from the schema, or every field of an unregistered type, are deep merged.
+func MergeSource(t string, a, b interface{}) (interface{}, error) {
+	am, err := sourceMap(a)
+	if err != nil {
+		return nil, err
+	}
+	bm, err := sourceMap(b)
+	if err != nil {
+		return nil, err
+	}
+	schema, ok := GetSourceSchema(t)
+	if !ok {
+		return types.MergeValues(am, bm), nil
+	}
+	res := make(map[interface{}]interface{})
+	for k, v := range am {
+		res[k] = v
+	}
+	for _, f := range schema.IdentityFields() {
+		bv, set := bm[f]
+		if set && !types.IsZeroValue(bv) && !types.ValuesEqual(am[f], bv) {
+			res = make(map[interface{}]interface{})
+			for _, id := range schema.IdentityFields() {
+				if v, ok := am[id]; ok {
+					res[id] = v
+				}
+			}
+			break
+		}
+	}
+	for k, v := range bm {
+		name, _ := k.(string)
+		if _, ok := schema.Field(name); ok {
+			res[k] = v
+		} else {
+			res[k] = types.MergeValues(res[k], v)
+		}
+	}
+	return res, nil
+}
+
+func sourceMap(i interface{}) (map[interface{}]interface{}, error) {
+	res := make(map[interface{}]interface{})
+	if i == nil {
+		return res, nil
+	}
+	d, err := yaml.Marshal(i)
+	if err != nil {
+		return res, err
+	}
+	err = yaml.Unmarshal(d, &res)
+	if err != nil {
+		return res, fmt.Errorf("source is not a map: %v", err)
+	}
+	return res, nil
+}
+
+func identity(names ...string) []SourceField {
+	var res []SourceField
+	for _, n := range names {
+		res = append(res, SourceField{Name: n, Identity: true})
+	}
+	return res
+}
+
+func mergeable(names ...string) []SourceField {
+	var res []SourceField
+	for _, n := range names {
+		res = append(res, SourceField{Name: n})
+	}
+	return res
+}
+
+func secret(names ...string) []SourceField {
+	var res []SourceField
+	for _, n := range names {
+		res = append(res, SourceField{Name: n, Secret: true})
+	}
+	return res
+}
+
+func schema(fields ...[]SourceField) SourceSchema {
+	res := SourceSchema{}
+	for _, f := range fields {
+		res.Fields = append(res.Fields, f...)
+	}
+	return res
+}
+
+func init() {
+	RegisterSourceSchema(GCSResourceType, schema(
+		identity("bucket", "regexp", "versioned_file"),
+		secret("json_key"),
+	))
+	RegisterSourceSchema(GithubReleaseResourceType, schema(
+		identity("owner", "repository"),
+		mergeable("github_api_url", "github_uploads_url", "insecure", "release",
+			"pre_release", "drafts", "tag_filter"),
+		secret("access_token"),
+	))
+	RegisterSourceSchema(BoshIOStemcellResourceType, schema(
+		identity("name"),
+		mergeable("force_regular", "tarball"),
+	))
+	RegisterSourceSchema(GitResourceType, schema(
+		identity("uri", "branch"),
+		mergeable("username", "paths", "ignore_paths", "skip_ssl_verification",
+			"tag_filter", "git_config", "disable_ci_skip", "commit_verification_keys",
+			"commit_verification_key_ids", "gpg_keyserver", "https_tunnel"),
+		secret("private_key", "password", "git_crypt_key"),
+	))
+	RegisterSourceSchema(MergeRequestResourceType, schema(
+		identity("uri"),
+		mergeable("username", "no_ssl", "skip_ssl_verification"),
+		secret("private_token", "private_key", "password"),
+	))
+	slack := schema(
+		mergeable("insecure", "ca_certs"),
+		secret("url"),
+	)
+	// the type is usually declared as slack
+	RegisterSourceSchema(SlackNotificationResourceType, slack)
+	RegisterSourceSchema(SlackResourceType, slack)
+	RegisterSourceSchema(PoolResourceType, schema(
+		identity("uri", "branch", "pool"),
+		mergeable("username", "retry_delay"),
+		secret("private_key", "password"),
+	))
+	RegisterSourceSchema(SemverResourceType, schema(
+		identity("driver", "uri", "branch", "file", "bucket", "key"),
+		mergeable("initial_version", "username", "git_user", "commit_message",
+			"access_key_id", "region_name", "endpoint", "disable_ssl"),
+		secret("private_key", "password", "secret_access_key", "json_key"),
+	))
+	RegisterSourceSchema(S3ResourceType, schema(
+		identity("bucket", "regexp", "versioned_file"),
+		mergeable("access_key_id", "region_name", "private", "cloudfront_url", "endpoint",
+			"disable_ssl", "skip_ssl_verification", "server_side_encryption",
+			"sse_kms_key_id", "use_v2_signing", "initial_path", "initial_version",
+			"initial_content_text", "initial_content_binary"),
+		secret("secret_access_key", "session_token"),
+	))
+	RegisterSourceSchema(TimeResourceType, schema(
+		identity("interval", "start", "stop", "location", "days"),
+	))
+	RegisterSourceSchema(RegistryImageResourceType, schema(
+		identity("repository", "tag"),
+		mergeable("username", "debug"),
+		secret("password"),
+	))
+	RegisterSourceSchema(DockerImageResourceType, schema(
+		identity("repository", "tag"),
+		mergeable("username", "aws_access_key_id", "insecure_registries",
+			"registry_mirror", "ca_certs", "client_certs", "max_concurrent_downloads",
+			"max_concurrent_uploads"),
+		secret("password", "aws_secret_access_key", "aws_session_token"),
+	))
+}
@@ -0,0 +1,99 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package resource
+
+import (
+	"reflect"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestCompareSource(t *testing.T) {
+	tests := []struct {
+		name string
+		t    string
+		a    string
+		b    string
+		want bool
+	}{
+		{
+			name: "identity fields match",
+			t:    GitResourceType,
+			a:    `{uri: "git@example.com:ci.git", branch: master, private_key: a}`,
+			b:    `{uri: "git@example.com:ci.git", branch: master, private_key: b, paths: [ci]}`,
+			want: true,
+		},
+		{
+			name: "identity field differs",
+			t:    GitResourceType,
+			a:    `{uri: "git@example.com:ci.git", branch: master}`,
+			b:    `{uri: "git@example.com:ci.git", branch: develop}`,
+		},
+		{
+			name: "omitted identity field",
+			t:    GitResourceType,
+			a:    `{uri: "git@example.com:ci.git", branch: ""}`,
+			b:    `{uri: "git@example.com:ci.git"}`,
+			want: true,
+		},
+		{
+			name: "unregistered types are deep compared",
+			t:    "pull-request",
+			a:    `{repository: org/ci, access_token: a}`,
+			b:    `{repository: org/ci, access_token: b}`,
+		},
+		{
+			name: "unregistered types with equal sources",
+			t:    "pull-request",
+			a:    `{repository: org/ci, labels: [ci]}`,
+			b:    `{labels: [ci], repository: org/ci, disable_forks: false}`,
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompareSource(tt.t, sourceValue(t, tt.a), sourceValue(t, tt.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResourceSetInsert(t *testing.T) {
+	rs := ResourceSet{}
+	inserts := []Resource{
+		{Name: "repo", Type: GitResourceType, Source: sourceValue(t, `{uri: "git@example.com:ci.git", branch: master}`)},
+		{Name: "repo", Type: GitResourceType, Source: sourceValue(t, `{uri: "git@example.com:ci.git", branch: master, private_key: ((key))}`)},
+		{Name: "repo", Type: GitResourceType, Source: sourceValue(t, `{uri: "git@example.com:ci.git", branch: develop}`)},
+	}
+	for _, r := range inserts {
+		if err := rs.Insert(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := rs.Get()
+	if len(got) != 2 {
+		t.Fatalf("got %d resources, want the first two merged", len(got))
+	}
+	want := sourceValue(t, `{uri: "git@example.com:ci.git", branch: master, private_key: ((key))}`)
+	if !reflect.DeepEqual(got[0].Source, want) {
+		t.Errorf("got source %v, want %v", got[0].Source, want)
+	}
+}
+
+func sourceValue(t *testing.T, data string) interface{} {
+	t.Helper()
+	res := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal([]byte(data), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
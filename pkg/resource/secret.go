@@ -0,0 +1,206 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package resource
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+)
+
+var (
+	// secretKeyRegexp matches field names holding credentials in sources of
+	// resource types without a registered schema, and in nested fields.
+	secretKeyRegexp = regexp.MustCompile(`(?i)(password|passphrase|secret|token|private_key|json_key|api_key|access_key$|credentials?$)`)
+	// secretValueRegexp matches values that are credentials whatever their
+	// field, e.g. PEM encoded private keys.
+	secretValueRegexp = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	varRegexp         = regexp.MustCompile(`^\(\([^()]+\)\)$`)
+	varNameRegexp     = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// Secret is a credential found in plain text in a source.
+type Secret struct {
+	// Path locates the secret, e.g. "resources[src].source.private_key".
+	Path string
+	// Var is the name of the variable replacing the secret once redacted.
+	Var   string
+	Value interface{}
+}
+
+// IsSecretField tells whether field name of the source of a resource of type
+// t holds a credential, according to the schema of t when it is registered
+// and to the field name otherwise. types are the declared custom types, see
+// SchemaType.
+func IsSecretField(t string, types []ResourceType, name string) bool {
+	if s, ok := GetSourceSchema(SchemaType(t, types)); ok {
+		if f, ok := s.Field(name); ok {
+			return f.Secret
+		}
+	}
+	return secretKeyRegexp.MatchString(name)
+}
+
+// IsVar tells whether v is a ((var)) reference.
+func IsVar(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && varRegexp.MatchString(strings.TrimSpace(s))
+}
+
+// RedactSource replaces the credentials found in plain text in source, the
+// source of a resource of type t, with ((prefix_field)) references. types
+// are the declared custom types, see SchemaType. It returns the redacted
+// source along with the secrets it replaced, path prefixing their location.
+func RedactSource(path, prefix, t string, types []ResourceType, source interface{}) (interface{}, []Secret, error) {
+	if source == nil {
+		return nil, nil, nil
+	}
+	m, err := sourceMap(source)
+	if err != nil {
+		return source, nil, err
+	}
+	var secrets []Secret
+	res := redact(path+".source", prefix, m, func(k string) bool { return IsSecretField(t, types, k) }, &secrets)
+	return res, secrets, nil
+}
+
+// RedactValue replaces the credentials found in plain text in v, e.g. the
+// body of a step, with ((prefix_field)) references. Credentials are told by
+// their field name or their value since no schema describes v. It returns the
+// redacted value along with the secrets it replaced, path prefixing their
+// location.
+func RedactValue(path, prefix string, v interface{}) (interface{}, []Secret, error) {
+	if v == nil {
+		return nil, nil, nil
+	}
+	d, err := yaml.Marshal(v)
+	if err != nil {
+		return v, nil, err
+	}
+	var generic interface{}
+	err = yaml.Unmarshal(d, &generic)
+	if err != nil {
+		return v, nil, err
+	}
+	var secrets []Secret
+	res := redact(path, prefix, generic, secretKeyRegexp.MatchString, &secrets)
+	return res, secrets, nil
+}
+
+func redact(path, prefix string, v interface{}, isSecret func(string) bool, secrets *[]Secret) interface{} {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		var keys []interface{}
+		res := make(map[interface{}]interface{})
+		for k, e := range vv {
+			keys = append(keys, k)
+			res[k] = e
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			e, ks := vv[k], fmt.Sprint(k)
+			kpath, kprefix := path+"."+ks, prefix+"_"+ks
+			if isSecret(ks) && isLiteral(e) {
+				res[k] = addSecret(kpath, kprefix, e, secrets)
+				continue
+			}
+			// nested fields are not described by schemas
+			res[k] = redact(kpath, kprefix, e, secretKeyRegexp.MatchString, secrets)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(vv))
+		for i, e := range vv {
+			res[i] = redact(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("%s_%d", prefix, i), e, secretKeyRegexp.MatchString, secrets)
+		}
+		return res
+	case string:
+		if secretValueRegexp.MatchString(vv) {
+			return addSecret(path, prefix, vv, secrets)
+		}
+	}
+	return v
+}
+
+func addSecret(path, name string, value interface{}, secrets *[]Secret) string {
+	name = varNameRegexp.ReplaceAllString(name, "_")
+	*secrets = append(*secrets, Secret{Path: path, Var: name, Value: value})
+	return "((" + name + "))"
+}
+
+// isLiteral tells whether v is a value set in plain text, as opposed to an
+// empty value or a ((var)) reference.
+func isLiteral(v interface{}) bool {
+	switch vv := v.(type) {
+	case nil:
+		return false
+	case string:
+		return vv != "" && !IsVar(vv)
+	case map[interface{}]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+// Redact returns r with the credentials of its source replaced by
+// ((resources_<name>_<field>)) references, along with the secrets replaced.
+// types are the declared custom types, see SchemaType.
+func (r *Resource) Redact(types []ResourceType) (Resource, []Secret, error) {
+	res := *r
+	var secrets []Secret
+	var err error
+	res.Source, secrets, err = RedactSource(fmt.Sprintf("resources[%s]", r.Name), "resources_"+r.Name, r.Type, types, r.Source)
+	return res, secrets, err
+}
+
+// Redact returns r with the credentials of its source replaced by
+// ((resource_types_<name>_<field>)) references, along with the secrets
+// replaced. Sources of resource types are the ones of resources of their
+// type.
+func (r *ResourceType) Redact(types []ResourceType) (ResourceType, []Secret, error) {
+	res := *r
+	var secrets []Secret
+	var err error
+	res.Source, secrets, err = RedactSource(fmt.Sprintf("resource_types[%s]", r.Name), "resource_types_"+r.Name, r.Type, types, r.Source)
+	return res, secrets, err
+}
+
+// SaveVarsSkeleton adds the variables replacing secrets to the vars file
+// filename, with empty values to be filled in. Variables already defined in
+// filename keep their value.
+func SaveVarsSkeleton(filename string, secrets []Secret) error {
+	content, err := ioutils.LoadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	vars := make(map[string]interface{})
+	err = yaml.Unmarshal([]byte(content), &vars)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	for _, s := range secrets {
+		if _, ok := vars[s.Var]; !ok {
+			vars[s.Var] = ""
+		}
+	}
+	b, err := yaml.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	err = ioutils.EnsureDir(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, b, 0600)
+}
@@ -0,0 +1,91 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package resource
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	types := []ResourceType{
+		{Name: "notify", Type: DockerImageResourceType, Source: map[interface{}]interface{}{
+			"repository": "cfcommunity/slack-notification-resource:v1.5.0",
+		}},
+	}
+	tests := []struct {
+		name   string
+		r      Resource
+		vars   []string
+		source map[interface{}]interface{}
+	}{
+		{
+			name: "slack type",
+			r: Resource{Name: "alert", Type: SlackResourceType, Source: map[interface{}]interface{}{
+				"url": "https://hooks.slack.com/services/T0/B0/x",
+			}},
+			vars:   []string{"resources_alert_url"},
+			source: map[interface{}]interface{}{"url": "((resources_alert_url))"},
+		},
+		{
+			name: "custom type pulling the slack image",
+			r: Resource{Name: "alert", Type: "notify", Source: map[interface{}]interface{}{
+				"url":      "https://hooks.slack.com/services/T0/B0/x",
+				"insecure": true,
+			}},
+			vars:   []string{"resources_alert_url"},
+			source: map[interface{}]interface{}{"url": "((resources_alert_url))", "insecure": true},
+		},
+		{
+			name: "vars are left alone",
+			r: Resource{Name: "repo", Type: GitResourceType, Source: map[interface{}]interface{}{
+				"uri":         "git@example.com:ci.git",
+				"private_key": "((repo_key))",
+				"password":    "secret",
+			}},
+			vars: []string{"resources_repo_password"},
+			source: map[interface{}]interface{}{
+				"uri":         "git@example.com:ci.git",
+				"private_key": "((repo_key))",
+				"password":    "((resources_repo_password))",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, secrets, err := tt.r.Redact(types)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var vars []string
+			for _, s := range secrets {
+				vars = append(vars, s.Var)
+			}
+			if !reflect.DeepEqual(vars, tt.vars) {
+				t.Errorf("got vars %q, want %q", vars, tt.vars)
+			}
+			if !reflect.DeepEqual(got.Source, tt.source) {
+				t.Errorf("got source %v, want %v", got.Source, tt.source)
+			}
+		})
+	}
+}
+
+func TestRedactResourceTypeVars(t *testing.T) {
+	r := Resource{Name: "img", Type: RegistryImageResourceType, Source: map[interface{}]interface{}{"password": "a"}}
+	rt := ResourceType{Name: "img", Type: RegistryImageResourceType, Source: map[interface{}]interface{}{"password": "b"}}
+	_, rs, err := r.Redact(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, rts, err := rt.Redact(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs) != 1 || len(rts) != 1 || rs[0].Var == rts[0].Var {
+		t.Errorf("got vars %v and %v, want one distinct var each", rs, rts)
+	}
+}
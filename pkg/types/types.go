@@ -6,6 +6,8 @@ Sniperkit-Bot
 package types
 
 import (
+	"reflect"
+
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -140,3 +142,94 @@ type InternalError string
 func (i InternalError) Error() string {
 	return string(i)
 }
+
+// MergeValues deep merges two values decoded from yaml: keys of b override
+// the ones of a, nested maps are merged recursively and any other value of b,
+// lists included, replaces the one of a unless it is nil.
+func MergeValues(a, b interface{}) interface{} {
+	if b == nil {
+		return a
+	}
+	am, ok := toInterfaceMap(a)
+	if !ok {
+		return b
+	}
+	bm, ok := toInterfaceMap(b)
+	if !ok {
+		return b
+	}
+	res := make(map[interface{}]interface{})
+	for k, v := range am {
+		res[k] = v
+	}
+	for k, v := range bm {
+		res[k] = MergeValues(res[k], v)
+	}
+	return res
+}
+
+func toInterfaceMap(i interface{}) (map[interface{}]interface{}, bool) {
+	switch v := i.(type) {
+	case map[interface{}]interface{}:
+		return v, true
+	case map[string]interface{}:
+		res := make(map[interface{}]interface{})
+		for k, vv := range v {
+			res[k] = vv
+		}
+		return res, true
+	}
+	return nil, false
+}
+
+// ValuesEqual deep compares two values decoded from yaml. Keys missing from
+// one map are equal to zero values in the other one, so that an omitted field
+// matches a field set to its default.
+func ValuesEqual(a, b interface{}) bool {
+	if IsZeroValue(a) && IsZeroValue(b) {
+		return true
+	}
+	am, aok := toInterfaceMap(a)
+	bm, bok := toInterfaceMap(b)
+	if aok && bok {
+		for k, v := range am {
+			if !ValuesEqual(v, bm[k]) {
+				return false
+			}
+		}
+		for k, v := range bm {
+			if _, ok := am[k]; !ok && !IsZeroValue(v) {
+				return false
+			}
+		}
+		return true
+	}
+	as, aok := a.([]interface{})
+	bs, bok := b.([]interface{})
+	if aok && bok {
+		if len(as) != len(bs) {
+			return false
+		}
+		for i := range as {
+			if !ValuesEqual(as[i], bs[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// IsZeroValue tells whether i is nil or the zero value of its type, empty
+// lists and maps included.
+func IsZeroValue(i interface{}) bool {
+	if i == nil {
+		return true
+	}
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(i, reflect.Zero(v.Type()).Interface())
+}
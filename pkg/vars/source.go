@@ -0,0 +1,218 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package vars
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+)
+
+// Source provides variable values.
+type Source interface {
+	// Get returns the value of variable name and whether it is defined.
+	Get(name string) (interface{}, bool, error)
+	// List returns the names of the variables defined.
+	List() ([]string, error)
+}
+
+// StaticVariables are variables defined in memory, from vars files or the
+// command line.
+type StaticVariables map[string]interface{}
+
+func (s StaticVariables) Get(name string) (interface{}, bool, error) {
+	v, ok := s[name]
+	return v, ok, nil
+}
+
+func (s StaticVariables) List() ([]string, error) {
+	var res []string
+	for k := range s {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// LoadVarsFile reads the variables defined in the yaml file filename.
+func LoadVarsFile(filename string) (StaticVariables, error) {
+	res := StaticVariables{}
+	data, err := ioutils.LoadFile(filename)
+	if err != nil {
+		return res, err
+	}
+	err = yaml.Unmarshal([]byte(data), &res)
+	if err != nil {
+		return res, berror.WithFile(berror.FromYAML(err), filename)
+	}
+	return res, nil
+}
+
+// ParseVarFlag parses a name=value variable definition, value being decoded
+// as yaml.
+func ParseVarFlag(s string) (string, interface{}, error) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return "", nil, fmt.Errorf("invalid var %q: expected name=value", s)
+	}
+	var v interface{}
+	err := yaml.Unmarshal([]byte(s[i+1:]), &v)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid var %q: %v", s, err)
+	}
+	return s[:i], v, nil
+}
+
+var envNameRegexp = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// EnvVariables reads variables from the environment: variable foo-bar is
+// read from PREFIX_FOO_BAR. A prefix is required so that unrelated
+// environment variables are not taken for variables.
+type EnvVariables struct {
+	Prefix string
+}
+
+func (e EnvVariables) envName(name string) string {
+	return e.Prefix + envNameRegexp.ReplaceAllString(strings.ToUpper(name), "_")
+}
+
+func (e EnvVariables) Get(name string) (interface{}, bool, error) {
+	s, ok := os.LookupEnv(e.envName(name))
+	if !ok {
+		return nil, false, nil
+	}
+	var v interface{}
+	err := yaml.Unmarshal([]byte(s), &v)
+	if err != nil {
+		return s, true, nil
+	}
+	return v, true, nil
+}
+
+// List returns nothing: names are not recoverable from environment variable
+// names, so environment variables are never reported unused.
+func (e EnvVariables) List() ([]string, error) {
+	return nil, nil
+}
+
+// FileStore is a local credential store: every variable is a yaml file of
+// a folder, team/foo being stored in <dir>/team/foo.yml. Names are relative
+// paths inside the folder, names with empty, "." or ".." elements are
+// rejected.
+type FileStore struct {
+	Dir string
+}
+
+func (f FileStore) path(name string) (string, error) {
+	for _, e := range strings.Split(name, "/") {
+		if e == "" || e == "." || e == ".." || strings.ContainsAny(e, `\:`) {
+			return "", fmt.Errorf("invalid var name %q: not a relative path of the store", name)
+		}
+	}
+	return filepath.Join(f.Dir, filepath.FromSlash(name)+".yml"), nil
+}
+
+func (f FileStore) Get(name string) (interface{}, bool, error) {
+	path, err := f.path(name)
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := ioutils.LoadFile(path)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var v interface{}
+	err = yaml.Unmarshal([]byte(data), &v)
+	if err != nil {
+		return nil, false, berror.WithFile(berror.FromYAML(err), path)
+	}
+	return v, true, nil
+}
+
+// Set stores the value of variable name, readable by its owner only.
+func (f FileStore) Set(name string, v interface{}) error {
+	path, err := f.path(name)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	err = ioutils.EnsureDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+func (f FileStore) List() ([]string, error) {
+	var res []string
+	err := filepath.Walk(f.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".yml" {
+			return nil
+		}
+		rel, err := filepath.Rel(f.Dir, path)
+		if err != nil {
+			return err
+		}
+		res = append(res, filepath.ToSlash(strings.TrimSuffix(rel, ".yml")))
+		return nil
+	})
+	sort.Strings(res)
+	return res, err
+}
+
+// MultiSource looks variables up in each of its sources in turn, the first
+// one defining a variable wins.
+type MultiSource []Source
+
+func (m MultiSource) Get(name string) (interface{}, bool, error) {
+	for _, s := range m {
+		v, found, err := s.Get(name)
+		if err != nil || found {
+			return v, found, err
+		}
+	}
+	return nil, false, nil
+}
+
+func (m MultiSource) List() ([]string, error) {
+	seen := make(map[string]bool)
+	var res []string
+	for _, s := range m {
+		names, err := s.List()
+		if err != nil {
+			return res, err
+		}
+		for _, n := range names {
+			if !seen[n] {
+				seen[n] = true
+				res = append(res, n)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
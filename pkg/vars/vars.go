@@ -0,0 +1,325 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package vars
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/yamlnode"
+)
+
+// varRegexp matches ((name)), ((name.field)) and ((source:name.field))
+// placeholders, as understood by Concourse.
+var varRegexp = regexp.MustCompile(`\(\(([-/\.\w]+:)?([-/\.\w]+)\)\)`)
+
+// Var is a ((var)) placeholder: ((name.field)) designates the field of the
+// value of name.
+type Var struct {
+	Source string
+	Name   string
+	Fields []string
+}
+
+func ParseVar(s string) (Var, error) {
+	m := varRegexp.FindStringSubmatch(s)
+	if m == nil || m[0] != s {
+		return Var{}, fmt.Errorf("invalid var %q", s)
+	}
+	return newVar(m), nil
+}
+
+func newVar(m []string) Var {
+	parts := strings.Split(m[2], ".")
+	return Var{
+		Source: strings.TrimSuffix(m[1], ":"),
+		Name:   parts[0],
+		Fields: parts[1:],
+	}
+}
+
+func (v Var) String() string {
+	res := strings.Join(append([]string{v.Name}, v.Fields...), ".")
+	if v.Source != "" {
+		res = v.Source + ":" + res
+	}
+	return "((" + res + "))"
+}
+
+// Reference is a placeholder found in a document, Path locating it, e.g.
+// "resources[0].source.password".
+type Reference struct {
+	Var  Var
+	Path string
+}
+
+// Find returns every placeholder referenced in v, a value decoded from yaml,
+// in document order.
+func Find(v interface{}) []Reference {
+	var res []Reference
+	walk("", v, func(path string, s string) {
+		for _, m := range varRegexp.FindAllStringSubmatch(s, -1) {
+			res = append(res, Reference{Var: newVar(m), Path: path})
+		}
+	})
+	return res
+}
+
+// Names returns the names of the variables referenced in v, sorted.
+func Names(v interface{}) []string {
+	seen := make(map[string]bool)
+	var res []string
+	for _, r := range Find(v) {
+		if !seen[r.Var.Name] {
+			seen[r.Var.Name] = true
+			res = append(res, r.Var.Name)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func walk(path string, v interface{}, fn func(path string, s string)) {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		var keys []interface{}
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			walk(join(path, fmt.Sprint(k)), vv[k], fn)
+		}
+	case yaml.MapSlice:
+		for _, item := range vv {
+			walk(join(path, fmt.Sprint(item.Key)), item.Value, fn)
+		}
+	case []interface{}:
+		for i, e := range vv {
+			walk(fmt.Sprintf("%s[%d]", path, i), e, fn)
+		}
+	case string:
+		fn(path, vv)
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// UndefinedError lists the variables that could not be resolved. It is
+// returned along with the interpolated value, the placeholders of these
+// variables being left for the credential manager of Concourse.
+type UndefinedError struct {
+	Names []string
+}
+
+func (e *UndefinedError) Error() string {
+	return fmt.Sprintf("undefined vars: %s", strings.Join(e.Names, ", "))
+}
+
+// Interpolate replaces the placeholders of v, a value decoded from yaml,
+// with their value in src. A string made of a single placeholder is replaced
+// by its value whatever its type, placeholders embedded in a longer string by
+// their value formatted as a string. Placeholders src does not define are
+// left as is and reported with an *UndefinedError, returned along with the
+// interpolated value. Placeholders naming a var source, e.g. ((vault:name)),
+// are left for that source and not looked up in src.
+func Interpolate(v interface{}, src Source) (interface{}, error) {
+	undefined := make(map[string]bool)
+	res, err := interpolate("", v, src, undefined)
+	if err != nil {
+		return res, err
+	}
+	if len(undefined) != 0 {
+		var names []string
+		for n := range undefined {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return res, &UndefinedError{Names: names}
+	}
+	return res, nil
+}
+
+// InterpolateString interpolates the yaml document data, keeping its
+// comments, the order of its keys and its anchors. Like Interpolate, the
+// variables src does not define are reported with an *UndefinedError,
+// returned along with the interpolated document.
+func InterpolateString(data string, src Source) (string, error) {
+	var v yaml.MapSlice
+	err := yaml.Unmarshal([]byte(data), &v)
+	if err != nil {
+		return data, berror.FromYAML(err)
+	}
+	res, err := Interpolate(v, src)
+	undefined, ok := err.(*UndefinedError)
+	if err != nil && !ok {
+		return data, err
+	}
+	doc, err := yamlnode.ParseDocument(data)
+	if err != nil {
+		return data, err
+	}
+	err = doc.Update(res)
+	if err != nil {
+		return data, err
+	}
+	out, err := doc.Encode()
+	if err != nil || undefined == nil {
+		return out, err
+	}
+	return out, undefined
+}
+
+func interpolate(path string, v interface{}, src Source, undefined map[string]bool) (interface{}, error) {
+	switch vv := v.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{})
+		for k, e := range vv {
+			r, err := interpolate(join(path, fmt.Sprint(k)), e, src, undefined)
+			if err != nil {
+				return v, err
+			}
+			res[k] = r
+		}
+		return res, nil
+	case yaml.MapSlice:
+		res := make(yaml.MapSlice, len(vv))
+		for i, item := range vv {
+			r, err := interpolate(join(path, fmt.Sprint(item.Key)), item.Value, src, undefined)
+			if err != nil {
+				return v, err
+			}
+			res[i] = yaml.MapItem{Key: item.Key, Value: r}
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(vv))
+		for i, e := range vv {
+			r, err := interpolate(fmt.Sprintf("%s[%d]", path, i), e, src, undefined)
+			if err != nil {
+				return v, err
+			}
+			res[i] = r
+		}
+		return res, nil
+	case string:
+		return interpolateString(path, vv, src, undefined)
+	}
+	return v, nil
+}
+
+func interpolateString(path string, s string, src Source, undefined map[string]bool) (interface{}, error) {
+	matches := varRegexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(s[last:m[0]])
+		last = m[1]
+		subs := make([]string, 3)
+		for i := range subs {
+			if m[2*i] >= 0 {
+				subs[i] = s[m[2*i]:m[2*i+1]]
+			}
+		}
+		v := newVar(subs)
+		if v.Source != "" {
+			// left for the var source of the pipeline
+			b.WriteString(subs[0])
+			continue
+		}
+		value, found, err := Resolve(v, src)
+		if err != nil {
+			return s, berror.WithPath(err, path)
+		}
+		if !found {
+			undefined[v.Name] = true
+			b.WriteString(subs[0])
+			continue
+		}
+		if m[0] == 0 && m[1] == len(s) {
+			return value, nil
+		}
+		switch value.(type) {
+		case map[interface{}]interface{}, yaml.MapSlice, []interface{}:
+			return s, berror.Errorf(path, "%s is not a scalar and can not be embedded in a string", v)
+		}
+		fmt.Fprintf(&b, "%v", value)
+	}
+	b.WriteString(s[last:])
+	return b.String(), nil
+}
+
+// Resolve returns the value of v in src, following its fields.
+func Resolve(v Var, src Source) (interface{}, bool, error) {
+	value, found, err := src.Get(v.Name)
+	if err != nil || !found {
+		return nil, found, err
+	}
+	for _, f := range v.Fields {
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil, false, fmt.Errorf("%s: %s is not a map", v, v.Name)
+		}
+		value, ok = m[f]
+		if !ok {
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// Report lists the variables of a document src can not resolve, and the ones
+// src defines that the document does not use. Variables of a var source, e.g.
+// ((vault:name)), are left out.
+type Report struct {
+	Undefined []string
+	Unused    []string
+}
+
+func Check(v interface{}, src Source) (Report, error) {
+	res := Report{}
+	used := make(map[string]bool)
+	undefined := make(map[string]bool)
+	for _, r := range Find(v) {
+		if r.Var.Source != "" {
+			continue
+		}
+		used[r.Var.Name] = true
+		_, found, err := Resolve(r.Var, src)
+		if err != nil {
+			return res, berror.WithPath(err, r.Path)
+		}
+		if !found && !undefined[r.Var.Name] {
+			undefined[r.Var.Name] = true
+			res.Undefined = append(res.Undefined, r.Var.Name)
+		}
+	}
+	sort.Strings(res.Undefined)
+	names, err := src.List()
+	if err != nil {
+		return res, err
+	}
+	for _, n := range names {
+		if !used[n] {
+			res.Unused = append(res.Unused, n)
+		}
+	}
+	sort.Strings(res.Unused)
+	return res, nil
+}
@@ -0,0 +1,405 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+// Package yamlnode edits yaml documents in place. Models are decoded and
+// encoded with yaml.v2, which drops comments, reorders keys and expands
+// anchors: a Document keeps the node tree of the original text instead and
+// only rewrites the nodes whose value changed.
+package yamlnode
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yamlv2 "gopkg.in/yaml.v2"
+	yaml "gopkg.in/yaml.v3"
+
+	berror "github.com/sniperkit/snk.fork.bulletin/pkg/error"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/ioutils"
+	"github.com/sniperkit/snk.fork.bulletin/pkg/types"
+)
+
+const mergeKey = "<<"
+
+// Document is a yaml document preserving comments, key order and anchors
+// across updates.
+type Document struct {
+	root *yaml.Node
+	// compactSeqs tells whether the sequences held by mappings are written
+	// at the indentation of their key, as yaml.v2 does, rather than indented
+	compactSeqs bool
+}
+
+func GetDocumentFromString(data string) *Document {
+	d, err := ParseDocument(data)
+	berror.CheckError(err)
+	return d
+}
+
+// ParseDocument is the error returning counterpart of GetDocumentFromString.
+func ParseDocument(data string) (*Document, error) {
+	d := &Document{compactSeqs: true}
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(data), &root)
+	if err != nil {
+		return d, berror.FromYAML(err)
+	}
+	if root.Kind == yaml.DocumentNode {
+		d.root = &root
+	}
+	if compact, ok := sequenceStyle(&root); ok {
+		d.compactSeqs = compact
+	}
+	return d, nil
+}
+
+// sequenceStyle tells whether the first block sequence held by a mapping of n
+// is written at the indentation of its key, ok being false when there is
+// none.
+func sequenceStyle(n *yaml.Node) (compact, ok bool) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			if v.Kind == yaml.SequenceNode && v.Style&yaml.FlowStyle == 0 && len(v.Content) != 0 {
+				return v.Column == k.Column, true
+			}
+		}
+	}
+	for _, c := range n.Content {
+		if compact, ok := sequenceStyle(c); ok {
+			return compact, true
+		}
+	}
+	return false, false
+}
+
+// LoadDocument reads the document defined in filename, a missing file being
+// an empty document.
+func LoadDocument(filename string) (*Document, error) {
+	data, err := ioutils.LoadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	d, err := ParseDocument(data)
+	if err != nil {
+		return d, berror.WithFile(err, filename)
+	}
+	return d, nil
+}
+
+// UpdateFile updates the document defined in filename with v, see Update.
+func UpdateFile(filename string, v interface{}) error {
+	d, err := LoadDocument(filename)
+	if err != nil {
+		return err
+	}
+	err = d.Update(v)
+	if err != nil {
+		return err
+	}
+	out, err := d.Encode()
+	if err != nil {
+		return err
+	}
+	err = ioutils.EnsureDir(filepath.Dir(filename))
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(out), 0644)
+}
+
+func (d *Document) String() string {
+	s, err := d.Encode()
+	berror.CheckError(err)
+	return s
+}
+
+// Encode is the error returning counterpart of String.
+func (d *Document) Encode() (string, error) {
+	if d.root == nil {
+		return "", nil
+	}
+	d.expandForwardAliases()
+	untagMergeKeys(d.root)
+	var b bytes.Buffer
+	e := yaml.NewEncoder(&b)
+	e.SetIndent(2)
+	err := e.Encode(d.root)
+	if err != nil {
+		return "", err
+	}
+	err = e.Close()
+	if err != nil {
+		return "", err
+	}
+	if d.compactSeqs {
+		return compactSequences(b.String())
+	}
+	return b.String(), nil
+}
+
+// compactSequences moves the block sequences held by mappings of data, as
+// written by the encoder which always indents them, to the indentation of
+// their key. The lines of a sequence are the ones following its key up to
+// the next line indented as much as the key or less.
+func compactSequences(data string) (string, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal([]byte(data), &root)
+	if err != nil {
+		return data, berror.FromYAML(err)
+	}
+	lines := strings.Split(data, "\n")
+	shifts := make([]int, len(lines))
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				if v.Kind != yaml.SequenceNode || v.Style&yaml.FlowStyle != 0 || v.Column <= k.Column {
+					continue
+				}
+				// lines are numbered from 1, k.Line indexes the line after
+				// the key
+				for l := k.Line; l < len(lines); l++ {
+					text := strings.TrimLeft(lines[l], " ")
+					if text != "" && len(lines[l])-len(text) < k.Column {
+						break
+					}
+					shifts[l] += v.Column - k.Column
+				}
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(&root)
+	for i, shift := range shifts {
+		indent := len(lines[i]) - len(strings.TrimLeft(lines[i], " "))
+		if shift > indent {
+			shift = indent
+		}
+		lines[i] = lines[i][shift:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// untagMergeKeys clears the tag of merge keys, which the encoder would
+// otherwise print explicitly as in "!!merge <<: *anchor".
+func untagMergeKeys(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i < len(n.Content); i += 2 {
+			if k := n.Content[i]; k.Kind == yaml.ScalarNode && k.Value == mergeKey {
+				k.Tag = ""
+			}
+		}
+	}
+	for _, c := range n.Content {
+		untagMergeKeys(c)
+	}
+}
+
+// Update makes the document hold v, a model or a value marshalled with
+// yaml.v2, rewriting only the nodes whose value differs:
+//   - nodes equal to their new value are kept along with their comments,
+//     style and anchors, aliases included;
+//   - mappings are updated key by key, new keys being appended. Keys v does
+//     not define are kept since models omit empty and unknown fields;
+//   - sequences of named items, e.g. resources or jobs, are matched by name,
+//     other sequences by index;
+//   - before an anchored node changes, its aliases are replaced with copies
+//     of its former value so that they keep it.
+func (d *Document) Update(v interface{}) error {
+	b, err := yamlv2.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var n yaml.Node
+	err = yaml.Unmarshal(b, &n)
+	if err != nil {
+		return berror.FromYAML(err)
+	}
+	if n.Kind != yaml.DocumentNode {
+		return nil
+	}
+	if d.root == nil || len(d.root.Content) == 0 {
+		d.root = &n
+		return nil
+	}
+	d.update(d.root.Content[0], n.Content[0])
+	return nil
+}
+
+func (d *Document) update(old, n *yaml.Node) {
+	if types.ValuesEqual(decode(old), decode(n)) {
+		return
+	}
+	if old.Kind != n.Kind || old.Kind == yaml.ScalarNode || old.Kind == yaml.AliasNode {
+		d.replace(old, n)
+		return
+	}
+	d.detach(old)
+	switch old.Kind {
+	case yaml.MappingNode:
+		d.updateMapping(old, n)
+	case yaml.SequenceNode:
+		d.updateSequence(old, n)
+	}
+}
+
+// replace overwrites old with n, keeping the comments of old and the quoting
+// of old strings.
+func (d *Document) replace(old, n *yaml.Node) {
+	d.detach(old)
+	res := *n
+	res.HeadComment, res.LineComment, res.FootComment = old.HeadComment, old.LineComment, old.FootComment
+	if old.Kind == yaml.ScalarNode && n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		switch old.Style {
+		case yaml.DoubleQuotedStyle, yaml.SingleQuotedStyle:
+			res.Style = old.Style
+		}
+	}
+	*old = res
+}
+
+func (d *Document) updateMapping(old, n *yaml.Node) {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if ov := mappingValue(old, k.Value); ov != nil {
+			d.update(ov, v)
+			continue
+		}
+		// the key may be missing because v is empty, or inherited with the
+		// same value through a merge key
+		var inherited interface{}
+		if m, ok := decode(old).(map[string]interface{}); ok {
+			inherited = m[k.Value]
+		}
+		if types.ValuesEqual(inherited, decode(v)) {
+			continue
+		}
+		old.Content = append(old.Content, k, v)
+	}
+}
+
+func (d *Document) updateSequence(old, n *yaml.Node) {
+	if !named(old) || !named(n) {
+		for i, item := range n.Content {
+			if i < len(old.Content) {
+				d.update(old.Content[i], item)
+			} else {
+				old.Content = append(old.Content, item)
+			}
+		}
+		if len(old.Content) > len(n.Content) {
+			old.Content = old.Content[:len(n.Content)]
+		}
+		return
+	}
+	used := make(map[*yaml.Node]bool)
+	var res []*yaml.Node
+	for _, item := range n.Content {
+		var match *yaml.Node
+		for _, o := range old.Content {
+			if !used[o] && name(o) == name(item) {
+				match = o
+				break
+			}
+		}
+		if match == nil {
+			res = append(res, item)
+			continue
+		}
+		used[match] = true
+		d.update(match, item)
+		res = append(res, match)
+	}
+	old.Content = res
+}
+
+// detach replaces the aliases of n, when it is anchored, with copies of its
+// value so that n can be changed without changing them.
+func (d *Document) detach(n *yaml.Node) {
+	if n.Anchor == "" {
+		return
+	}
+	var walk func(*yaml.Node)
+	walk = func(c *yaml.Node) {
+		for _, e := range c.Content {
+			if e.Kind == yaml.AliasNode && e.Alias == n {
+				cp := deepCopy(n)
+				cp.HeadComment, cp.LineComment, cp.FootComment = e.HeadComment, e.LineComment, e.FootComment
+				*e = *cp
+				continue
+			}
+			walk(e)
+		}
+	}
+	walk(d.root)
+}
+
+// deepCopy copies n without its anchors, aliases pointing to their original
+// anchor.
+func deepCopy(n *yaml.Node) *yaml.Node {
+	res := *n
+	res.Anchor = ""
+	if n.Kind == yaml.AliasNode {
+		return &res
+	}
+	res.Content = make([]*yaml.Node, len(n.Content))
+	for i, c := range n.Content {
+		res.Content[i] = deepCopy(c)
+	}
+	return &res
+}
+
+func decode(n *yaml.Node) interface{} {
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return nil
+	}
+	return v
+}
+
+func resolve(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if k := n.Content[i]; k.Value == key && k.Value != mergeKey {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func name(n *yaml.Node) string {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		return ""
+	}
+	v := mappingValue(n, "name")
+	if v == nil || v.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return v.Value
+}
+
+// named tells whether every item of sequence n has a name.
+func named(n *yaml.Node) bool {
+	for _, item := range n.Content {
+		if name(item) == "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package yamlnode
+
+import (
+	"testing"
+)
+
+func TestEncodeKeepsSequenceIndentation(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "compact sequences",
+			data: `resources:
+- name: repo
+  type: git
+  source:
+    paths:
+    - ci
+    - src
+jobs:
+- name: unit
+  plan:
+  - get: repo
+  - task: unit
+    config:
+      run:
+        path: sh
+        args:
+        - -c
+        - |
+          make test
+          - not a sequence:
+            - item
+`,
+		},
+		{
+			name: "indented sequences",
+			data: `resources:
+  - name: repo
+    type: git
+    source:
+      paths:
+        - ci
+jobs:
+  - name: unit
+    plan:
+      - get: repo
+`,
+		},
+		{
+			name: "comments and flow sequences",
+			data: `# pipeline
+groups:
+# all jobs
+- name: all
+  jobs: [unit]
+  resources:
+  - repo # the repository
+# the end
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := ParseDocument(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.Encode()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.data {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.data)
+			}
+		})
+	}
+}
+
+func TestUpdateKeepsSequenceIndentation(t *testing.T) {
+	d, err := ParseDocument("resources:\n- name: repo\n  type: git\n  source:\n    paths:\n    - ci\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Update(map[string]interface{}{
+		"resources": []interface{}{
+			map[string]interface{}{"name": "repo", "type": "git", "source": map[string]interface{}{"paths": []string{"ci", "src"}}},
+			map[string]interface{}{"name": "img", "type": "registry-image"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "resources:\n- name: repo\n  type: git\n  source:\n    paths:\n    - ci\n    - src\n- name: img\n  type: registry-image\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
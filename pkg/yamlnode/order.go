@@ -0,0 +1,102 @@
+/*
+Sniperkit-Bot
+- Status: analyzed
+*/
+
+package yamlnode
+
+import (
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Root returns the top level node of the document, nil when it is empty.
+// Nodes may be reordered in place, e.g. with OrderKeys or SortByName, their
+// comments moving along with them.
+func (d *Document) Root() *yaml.Node {
+	if d.root == nil || len(d.root.Content) == 0 {
+		return nil
+	}
+	return d.root.Content[0]
+}
+
+// MappingValue returns the value of key in mapping n, nil when n is not a
+// mapping or does not define key.
+func MappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	return mappingValue(n, key)
+}
+
+// OrderKeys reorders the keys of mapping n: the keys of order first, in that
+// order, then the other keys in their current order.
+func OrderKeys(n *yaml.Node, order []string) {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return
+	}
+	rank := make(map[string]int)
+	for i, k := range order {
+		rank[k] = i
+	}
+	type pair struct {
+		key, value *yaml.Node
+	}
+	var pairs []pair
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		pairs = append(pairs, pair{n.Content[i], n.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ri, ok := rank[pairs[i].key.Value]
+		if !ok {
+			ri = len(order)
+		}
+		rj, ok := rank[pairs[j].key.Value]
+		if !ok {
+			rj = len(order)
+		}
+		return ri < rj
+	})
+	n.Content = n.Content[:0]
+	for _, p := range pairs {
+		n.Content = append(n.Content, p.key, p.value)
+	}
+}
+
+// SortByName sorts the items of sequence n by name, items without a name
+// going last in their current order.
+func SortByName(n *yaml.Node) {
+	if n == nil || n.Kind != yaml.SequenceNode {
+		return
+	}
+	sort.SliceStable(n.Content, func(i, j int) bool {
+		ni, nj := name(n.Content[i]), name(n.Content[j])
+		if ni == "" || nj == "" {
+			return ni != "" && nj == ""
+		}
+		return ni < nj
+	})
+}
+
+// expandForwardAliases replaces the aliases found before their anchor in
+// document order, e.g. once nodes are reordered, with copies of the anchored
+// value: such aliases are not valid yaml.
+func (d *Document) expandForwardAliases() {
+	seen := make(map[*yaml.Node]bool)
+	var walk func(*yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Anchor != "" {
+			seen[n] = true
+		}
+		for _, c := range n.Content {
+			if c.Kind == yaml.AliasNode && c.Alias != nil && !seen[c.Alias] {
+				cp := deepCopy(c.Alias)
+				cp.HeadComment, cp.LineComment, cp.FootComment = c.HeadComment, c.LineComment, c.FootComment
+				*c = *cp
+			}
+			walk(c)
+		}
+	}
+	walk(d.root)
+}